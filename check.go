@@ -0,0 +1,67 @@
+package gobin
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/knaka/gobin/minlib"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// CheckManifest validates the manifest file, the files it includes or inherits, and the lock file. It reports all the problems found, with the line numbers where available, instead of stopping at the first one.
+func CheckManifest(opts ...Option) (err error) {
+	params := newInstallParams()
+	for _, opt := range opts {
+		if err = opt(params); err != nil {
+			return
+		}
+	}
+	global := params.optGlobal != nil && *params.optGlobal
+	confDirPath, _, err := params.dirs()
+	if err != nil {
+		return
+	}
+	var errs []error
+	filePath, err := manifestFilePath(confDirPath)
+	if err != nil {
+		return
+	}
+	if filePath != "" {
+		if _, err_ := readLayeredManifest(filePath); err_ != nil {
+			errs = append(errs, err_)
+		} else if manifest, err_ := parseManifest(confDirPath); err_ == nil {
+			graph := &depGraphT{manifest: manifest}
+			if !global {
+				graph.goModDef, _ = parseGoMod(confDirPath)
+			}
+			if err_ := graph.checkCycles(); err_ != nil {
+				errs = append(errs, err_)
+			}
+			if _, err_ := graph.requiredVersions(); err_ != nil {
+				errs = append(errs, err_)
+			}
+		}
+	}
+	pkgVerLockMap, lineNos, err_ := minlib.PkgVerLockMapWithLines(confDirPath)
+	if err_ != nil {
+		errs = append(errs, err_)
+	}
+	lockPath := filepath.Join(confDirPath, maniLockBase)
+	var pkgs []string
+	for pkg := range pkgVerLockMap {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		if err_ := module.CheckPath(pkg); err_ != nil {
+			errs = append(errs, &manifestError{lockPath, lineNos[pkg], fmt.Sprintf("malformed package path “%s”: %v", pkg, err_)})
+		}
+		if ver := pkgVerLockMap[pkg]; !semver.IsValid(ver) {
+			errs = append(errs, &manifestError{lockPath, lineNos[pkg], fmt.Sprintf("invalid locked version “%s” of %s", ver, pkg)})
+		}
+	}
+	return errors.Join(errs...)
+}
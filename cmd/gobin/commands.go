@@ -0,0 +1,468 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	. "github.com/knaka/go-utils"
+	"github.com/knaka/gobin"
+	"github.com/knaka/gobin/log"
+	"github.com/knaka/gobin/minlib"
+	"github.com/knaka/gobin/vlog"
+)
+
+var shimStrategies = func() (ret []string) {
+	for _, strategy := range minlib.ShimStrategies {
+		ret = append(ret, string(strategy))
+	}
+	return
+}()
+
+var shellNames = []string{gobin.ShellSh, gobin.ShellBash, gobin.ShellZsh, gobin.ShellFish, gobin.ShellPowerShell}
+
+// groupFlag defines the “--group” flag on the flag set, and returns the function to get the specified groups.
+func groupFlag(flags *flagSetT) (groups func() []string) {
+	group := ""
+	flags.stringVar(&group, "group", "G", "groups", "Target only the packages in the comma-separated groups.")
+	return func() []string {
+		if group == "" {
+			return nil
+		}
+		return strings.Split(group, ",")
+	}
+}
+
+// subCmds are the subcommands in the order of the usage.
+var subCmds []*subCmdT
+
+func init() {
+	subCmds = []*subCmdT{
+		{
+			name:     "list",
+			summary:  "List packages listed in the manifest file “Gobinfile” and the files which define them.\n\n“--json” prints them as a JSON array, and “--format” prints them with the text/template format like “go list -f”.",
+			maxArgs:  0,
+			toolArgs: 0,
+			define: func(flags *flagSetT, opts *globalOptsT) func(args []string) error {
+				groups := groupFlag(flags)
+				asJSON := false
+				flags.boolVar(&asJSON, "json", "", "Print the entries as a JSON array.")
+				format := ""
+				flags.stringVar(&format, "format", "f", "template", "Print each entry with the text/template format, e.g. “{{.Pkg}}@{{.LockedVersion}}”.")
+				return func(args []string) (err error) {
+					var tmpl *template.Template
+					if format != "" {
+						// The syntax is checked before listing, and the fields are checked by each entry.
+						if tmpl, err = template.New("list").Parse(format); err != nil {
+							return
+						}
+					}
+					l, err_ := gobin.ListEx(
+						gobin.Global(opts.global),
+						gobin.WithGroups(groups()...),
+					)
+					if err_ != nil {
+						return err_
+					}
+					if asJSON {
+						encoder := json.NewEncoder(os.Stdout)
+						encoder.SetIndent("", "\t")
+						if l == nil {
+							l = []*gobin.ListEntry{}
+						}
+						return encoder.Encode(l)
+					}
+					if tmpl != nil {
+						return printListEntries(os.Stdout, tmpl, l)
+					}
+					var wd string
+					if wd, err = os.Getwd(); err != nil {
+						return
+					}
+					for _, entry := range l {
+						fmt.Printf("%s@%s -> %s%s (%s)\n",
+							entry.Pkg,
+							entry.Version,
+							Ternary(entry.LockedVersion == "latest",
+								"undefined",
+								entry.LockedVersion,
+							),
+							Ternary(len(entry.Groups) > 0,
+								" ["+strings.Join(entry.Groups, ",")+"]",
+								"",
+							),
+							relPath(wd, entry.Source),
+						)
+						if entry.Description != "" {
+							fmt.Printf("    %s\n", entry.Description)
+						}
+					}
+					return
+				}
+			},
+		},
+		{
+			name:     "run",
+			args:     "[--] <name> [<args>...]",
+			summary:  "Run the specified program package.\n\nThe arguments after the name are passed to the program as they are, including the ones which look like flags.",
+			minArgs:  1,
+			maxArgs:  -1,
+			toolArgs: 1,
+			define: func(_ *flagSetT, opts *globalOptsT) func(args []string) error {
+				return func(args []string) (err error) {
+					_, err = gobin.RunEx(args,
+						gobin.WithStdin(os.Stdin),
+						gobin.WithStdout(os.Stdout),
+						gobin.WithStderr(os.Stderr),
+						gobin.Global(opts.global),
+					)
+					return
+				}
+			},
+		},
+		{
+			name:     "which",
+			args:     "<name>",
+			summary:  "Print the path of the binary of the program package without running it.\n\n“--install” installs it if missing.",
+			minArgs:  1,
+			maxArgs:  1,
+			toolArgs: 1,
+			define: func(flags *flagSetT, opts *globalOptsT) func(args []string) error {
+				shouldInstall := false
+				flags.boolVar(&shouldInstall, "install", "i", "Install the program package if not installed yet.")
+				return func(args []string) (err error) {
+					cmdPath, err_ := gobin.WhichEx(args[0],
+						gobin.Global(opts.global),
+						gobin.InstallIfMissing(shouldInstall),
+					)
+					if err_ != nil {
+						return err_
+					}
+					fmt.Println(cmdPath)
+					return
+				}
+			},
+		},
+		{
+			name:    "env",
+			summary: "Print the script which puts the installed tools and the Go SDK on PATH, for “eval” or direnv's “.envrc”.",
+			maxArgs: 0,
+			define: func(flags *flagSetT, opts *globalOptsT) func(args []string) error {
+				shell := defaultShellName()
+				flags.stringVar(&shell, "shell", "", "shell", "Shell to print the script for: sh, bash, zsh, fish or powershell.", shellNames...)
+				return func(args []string) (err error) {
+					script, err_ := gobin.EnvScriptEx(shell, gobin.Global(opts.global))
+					if err_ != nil {
+						return err_
+					}
+					fmt.Print(script)
+					return
+				}
+			},
+		},
+		{
+			name:    "shell",
+			summary: "Spawn a subshell with the installed tools and the Go SDK on PATH.",
+			maxArgs: 0,
+			define: func(_ *flagSetT, opts *globalOptsT) func(args []string) error {
+				return func(args []string) (err error) {
+					cmd, err_ := gobin.ShellCommandEx(gobin.Global(opts.global))
+					if err_ != nil {
+						return err_
+					}
+					vlog.Printf("Spawning %s\n", cmd.Path)
+					// The exit status of the subshell is passed through by the caller.
+					return cmd.Run()
+				}
+			},
+		},
+		{
+			name:     "install",
+			args:     "[<name>...]",
+			summary:  "Install the specified package(s).\n\nIf only groups are specified, install all packages in the groups.",
+			maxArgs:  -1,
+			toolArgs: -1,
+			define: func(flags *flagSetT, opts *globalOptsT) func(args []string) error {
+				groups := groupFlag(flags)
+				return func(args []string) (err error) {
+					_, err = gobin.InstallEx(args,
+						gobin.Global(opts.global),
+						gobin.WithGroups(groups()...),
+					)
+					return
+				}
+			},
+		},
+		{
+			name:    "shims",
+			summary: "Create the shims of all the program packages in the gobin directory, which install the locked version on first use.\n\nPut the directory on PATH to run them directly. “--strategy cmd” creates “.cmd” wrappers for Windows without symlinks.",
+			maxArgs: 0,
+			define: func(flags *flagSetT, opts *globalOptsT) func(args []string) error {
+				groups := groupFlag(flags)
+				strategy := ""
+				flags.stringVar(&strategy, "strategy", "", "strategy", "Way to create the shims: symlink, hardlink, copy or cmd. By default, symlink, hardlink and copy are tried in order.", shimStrategies...)
+				return func(args []string) (err error) {
+					shimPaths, err_ := gobin.ShimsEx(
+						gobin.Global(opts.global),
+						gobin.WithGroups(groups()...),
+						gobin.WithShimStrategy(minlib.ShimStrategy(strategy)),
+					)
+					if err_ != nil {
+						return err_
+					}
+					for _, shimPath := range shimPaths {
+						vlog.Printf("Created shim %s\n", shimPath)
+					}
+					return
+				}
+			},
+		},
+		{
+			name:    "generate",
+			args:    "[--] [<args>...]",
+			summary: "Install all the program packages up front and run “go generate” with the arguments and with the gobin directory on PATH.\n\nThe “//go:generate” directives can run the tools by their base names without “-command”. The directives annotated with “//go:generate_input” are skipped if the inputs, the tool version and the arguments are not changed since the last run. Put “--” before the flags of “go generate”.",
+			maxArgs: -1,
+			define: func(flags *flagSetT, opts *globalOptsT) func(args []string) error {
+				groups := groupFlag(flags)
+				force := false
+				flags.boolVar(&force, "force", "", "Run all the directives even if their inputs are not changed.")
+				return func(args []string) (err error) {
+					_, err = gobin.GenerateEx(args,
+						gobin.ForceGenerate(force),
+						gobin.WithStdin(os.Stdin),
+						gobin.WithStdout(os.Stdout),
+						gobin.WithStderr(os.Stderr),
+						gobin.Global(opts.global),
+						gobin.WithGroups(groups()...),
+					)
+					return
+				}
+			},
+		},
+		{
+			name:     "update",
+			args:     "[<name>...]",
+			summary:  "Update the specified “@latest” program package(s).\n\nIf no package is specified, update all packages.",
+			maxArgs:  -1,
+			toolArgs: -1,
+			define: func(flags *flagSetT, opts *globalOptsT) func(args []string) error {
+				groups := groupFlag(flags)
+				return func(args []string) (err error) {
+					return gobin.UpdateEx(args,
+						gobin.Global(opts.global),
+						gobin.WithGroups(groups()...),
+					)
+				}
+			},
+		},
+		{
+			name:     "help",
+			args:     "[<command> | <name>...]",
+			summary:  "Show this help, the usage of the command, or the description, version, tags, install path and required commands of the specified program package(s).",
+			maxArgs:  -1,
+			toolArgs: -1,
+			define: func(_ *flagSetT, opts *globalOptsT) func(args []string) error {
+				return func(args []string) (err error) {
+					if len(args) == 0 {
+						printUsage(os.Stdout)
+						return
+					}
+					if subCmd := lookupSubCmd(args[0]); subCmd != nil && len(args) == 1 {
+						flags, _ := subCmd.flagSet(opts)
+						subCmd.printUsage(os.Stdout, flags)
+						return
+					}
+					for _, name := range args {
+						entry, err_ := gobin.Lookup(name, gobin.Global(opts.global))
+						if err_ != nil {
+							return err_
+						}
+						printToolHelp(entry)
+					}
+					return
+				}
+			},
+		},
+		{
+			name:    "check",
+			summary: "Validate the manifest file and the lock file, reporting all the problems found.",
+			maxArgs: 0,
+			define: func(_ *flagSetT, opts *globalOptsT) func(args []string) error {
+				return func(args []string) (err error) {
+					return gobin.CheckManifest(gobin.Global(opts.global))
+				}
+			},
+		},
+		{
+			name:     "tree",
+			args:     "[<name>...]",
+			summary:  "Print the tree of the commands which the program packages require through the “requires” option.\n\nWithout names, the roots are the packages which no other package requires.",
+			maxArgs:  -1,
+			toolArgs: -1,
+			define: func(flags *flagSetT, opts *globalOptsT) func(args []string) error {
+				groups := groupFlag(flags)
+				return func(args []string) (err error) {
+					roots, err_ := gobin.DepTreeEx(args,
+						gobin.Global(opts.global),
+						gobin.WithGroups(groups()...),
+					)
+					if err_ != nil {
+						return err_
+					}
+					for _, root := range roots {
+						fmt.Println(depNodeLabel(root))
+						printDepTree(os.Stdout, root, "")
+					}
+					return
+				}
+			},
+		},
+		{
+			name:     "why",
+			args:     "<name>",
+			summary:  "Print the chains of the program packages which require the command through the “requires” option, like “go mod why”.",
+			minArgs:  1,
+			maxArgs:  1,
+			toolArgs: 1,
+			define: func(_ *flagSetT, opts *globalOptsT) func(args []string) error {
+				return func(args []string) (err error) {
+					chains, err_ := gobin.WhyEx(args[0], gobin.Global(opts.global))
+					if err_ != nil {
+						return err_
+					}
+					fmt.Printf("# %s\n", args[0])
+					if len(chains) == 0 {
+						fmt.Printf("(%s is not required by any package)\n", args[0])
+					}
+					for i, chain := range chains {
+						if i > 0 {
+							fmt.Println()
+						}
+						for _, pkg := range chain {
+							fmt.Println(pkg)
+						}
+					}
+					return
+				}
+			},
+		},
+		{
+			name:    "init",
+			args:    "[<dir>]",
+			summary: "Set up the directory to use gobin.\n\nCreate the manifest file “Gobinfile” unless it exists, write the bootstrap scripts “cmd-gobin” (sh), “cmd-gobin.cmd” (cmd.exe) and “cmd-gobin.go” (go run), and add “.gobin” to “.gitignore”.",
+			maxArgs: 1,
+			define: func(flags *flagSetT, opts *globalOptsT) func(args []string) error {
+				seed := ""
+				flags.stringVar(&seed, "from", "", "source", "Seed the manifest file from the imports of “tools.go” (tools-go) or from the binaries in $GOBIN or ~/go/bin (gobin).", gobin.SeedToolsGo, gobin.SeedGobin)
+				noBootstrap := false
+				flags.boolVar(&noBootstrap, "no-bootstrap", "", "Do not write the bootstrap scripts.")
+				return func(args []string) (err error) {
+					initOpts := []gobin.Option{
+						gobin.WithSeed(seed),
+						gobin.Bootstrap(!noBootstrap),
+					}
+					if len(args) > 0 {
+						initOpts = append(initOpts, gobin.WithDir(args[0]))
+					}
+					filePaths, err := gobin.InitProject(initOpts...)
+					for _, filePath := range filePaths {
+						log.Printf("Wrote %s\n", filePath)
+					}
+					return
+				}
+			},
+		},
+		{
+			name:      "migrate",
+			args:      "<line|toml|tools-go>",
+			summary:   "Convert the manifest file to “Gobinfile” (line) or “Gobinfile.toml” (toml) format, or migrate the tools imported by “tools.go” to the manifest (tools-go).\n\nThe tools from “tools.go” are added as “@latest” and locked to the versions in go.mod. “--clean-up” removes the imports from “tools.go” and runs “go mod tidy”.",
+			minArgs:   1,
+			maxArgs:   1,
+			argValues: []string{gobin.ManifestFormatLine, gobin.ManifestFormatTOML, gobin.ManifestFormatToolsGo},
+			define: func(flags *flagSetT, opts *globalOptsT) func(args []string) error {
+				cleanUp := false
+				flags.boolVar(&cleanUp, "clean-up", "", "With tools-go, remove the migrated imports from “tools.go” and run “go mod tidy”.")
+				return func(args []string) (err error) {
+					if args[0] == gobin.ManifestFormatToolsGo {
+						_, err = gobin.MigrateToolsGo(
+							gobin.CleanUpToolsGo(cleanUp),
+						)
+						return
+					}
+					return gobin.MigrateManifest(args[0],
+						gobin.Global(opts.global),
+					)
+				}
+			},
+		},
+		{
+			name:    "export",
+			summary: "Print the manifest entries with the locked versions as go.mod “tool” directives (go-tool), a “tools.go” file (tools-go) or a shell script which installs them (shell).\n\nOnly the shell script keeps the build tags, linker flags and environment variables.",
+			maxArgs: 0,
+			define: func(flags *flagSetT, opts *globalOptsT) func(args []string) error {
+				groups := groupFlag(flags)
+				format := gobin.ExportFormatGoTool
+				flags.stringVar(&format, "format", "f", "format", "Export format: go-tool, tools-go or shell. The default is go-tool.", gobin.ExportFormatGoTool, gobin.ExportFormatToolsGo, gobin.ExportFormatShell)
+				return func(args []string) (err error) {
+					if err_ := gobin.ExportManifest(format,
+						gobin.Global(opts.global),
+						gobin.WithGroups(groups()...),
+					); err_ != nil {
+						return err_
+					}
+					return
+				}
+			},
+		},
+		{
+			name:      "completion",
+			args:      "<bash|zsh|fish|powershell>",
+			summary:   "Print the script which completes the commands, flags and tool names for the shell.",
+			minArgs:   1,
+			maxArgs:   1,
+			argValues: []string{gobin.ShellBash, gobin.ShellZsh, gobin.ShellFish, gobin.ShellPowerShell},
+			define: func(_ *flagSetT, _ *globalOptsT) func(args []string) error {
+				return func(args []string) (err error) {
+					script, err_ := completionScript(args[0])
+					if err_ != nil {
+						return err_
+					}
+					fmt.Print(script)
+					return
+				}
+			},
+		},
+		{
+			name:    completeCmdBase,
+			args:    "[<words>...]",
+			summary: "Print the completion candidates of the last word.",
+			hidden:  true,
+			rawArgs: true,
+			define: func(_ *flagSetT, _ *globalOptsT) func(args []string) error {
+				return func(args []string) (err error) {
+					candidates, err_ := complete(args)
+					if err_ != nil {
+						return err_
+					}
+					for _, candidate := range candidates {
+						fmt.Println(candidate)
+					}
+					return
+				}
+			},
+		},
+	}
+}
+
+// exitCode returns the exit code of the child process if the error is from it.
+func exitCode(err error) (code int, ok bool) {
+	var errExit *exec.ExitError
+	if errors.As(err, &errExit) && errExit != nil {
+		return errExit.ExitCode(), true
+	}
+	return
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/knaka/gobin"
+)
+
+// completeCmdBase is the hidden subcommand which the completion scripts call back. The arguments are the words after “gobin” on the command line, the last of which is the word being completed.
+const completeCmdBase = "__complete"
+
+// filterPrefix returns the candidates which start with the prefix.
+func filterPrefix(candidates []string, prefix string) (ret []string) {
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			ret = append(ret, candidate)
+		}
+	}
+	return
+}
+
+// flagNames returns the names of the flags with the leading dashes.
+func flagNames(flags *flagSetT) (names []string) {
+	for _, spec := range flags.specs {
+		names = append(names, spec.names()...)
+	}
+	return
+}
+
+// complete returns the candidates of the last word of the command line. The words are the ones after “gobin”.
+func complete(words []string) (candidates []string, err error) {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+	words = words[:len(words)-1]
+	opts := &globalOptsT{}
+	globalFlags := newFlagSet("gobin")
+	defineGlobalFlags(globalFlags, opts)
+	// Errors are ignored because the command line is incomplete.
+	_ = globalFlags.Parse(words)
+	words = globalFlags.Args()
+	if len(words) == 0 {
+		if strings.HasPrefix(current, "-") {
+			return filterPrefix(flagNames(globalFlags), current), nil
+		}
+		for _, subCmd := range subCmds {
+			if !subCmd.hidden && strings.HasPrefix(subCmd.name, current) {
+				candidates = append(candidates, subCmd.name)
+			}
+		}
+		return
+	}
+	subCmd := lookupSubCmd(words[0])
+	if subCmd == nil || subCmd.hidden {
+		return
+	}
+	flags, _ := subCmd.flagSet(opts)
+	words = words[1:]
+	// The value of the flag in the form of “--flag value”.
+	if len(words) > 0 {
+		last := words[len(words)-1]
+		if strings.HasPrefix(last, "-") && !strings.Contains(last, "=") {
+			if spec := flags.lookupSpec(last); spec != nil && spec.arg != "" {
+				return filterPrefix(spec.values, current), nil
+			}
+		}
+	}
+	// The value of the flag in the form of “--flag=value”.
+	if name, value, ok := strings.Cut(current, "="); ok && strings.HasPrefix(current, "-") {
+		if spec := flags.lookupSpec(name); spec != nil {
+			for _, candidate := range filterPrefix(spec.values, value) {
+				candidates = append(candidates, name+"="+candidate)
+			}
+		}
+		return
+	}
+	// The flags are parsed only up to the first positional argument as on running.
+	_ = flags.Parse(words)
+	nArgs := flags.NArg()
+	if strings.HasPrefix(current, "-") && nArgs == 0 {
+		return filterPrefix(flagNames(flags), current), nil
+	}
+	if subCmd.toolArgs < 0 || nArgs < subCmd.toolArgs {
+		return gobin.CompleteToolsEx(current, gobin.Global(opts.global))
+	}
+	if nArgs == 0 {
+		return filterPrefix(subCmd.argValues, current), nil
+	}
+	return
+}
+
+const bashCompletion = `# bash completion for gobin
+_gobin() {
+	local IFS=$'\n'
+	COMPREPLY=($(gobin __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _gobin gobin
+`
+
+const zshCompletion = `#compdef gobin
+_gobin() {
+	local -a candidates
+	candidates=("${(@f)$(gobin __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	candidates=(${candidates:#})
+	if (( ${#candidates} )); then
+		compadd -- $candidates
+	else
+		_files
+	fi
+}
+compdef _gobin gobin
+`
+
+const fishCompletion = `# fish completion for gobin
+function __gobin_complete
+	set -l tokens (commandline -opc)
+	set -e tokens[1]
+	gobin __complete $tokens (commandline -ct) 2>/dev/null
+end
+complete -c gobin -f -a '(__gobin_complete)'
+`
+
+// The empty word to complete is passed as is only by PowerShell 7.3 or later.
+const powerShellCompletion = `# PowerShell completion for gobin
+Register-ArgumentCompleter -Native -CommandName gobin -ScriptBlock {
+	param($wordToComplete, $commandAst, $cursorPosition)
+	$words = @($commandAst.CommandElements | Select-Object -Skip 1 | Where-Object { $_.Extent.EndOffset -lt $cursorPosition } | ForEach-Object { $_.ToString() })
+	gobin __complete @words "$wordToComplete" 2>$null | ForEach-Object {
+		[System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+	}
+}
+`
+
+// completionScript returns the completion script for the shell.
+func completionScript(shell string) (script string, err error) {
+	switch shell {
+	case gobin.ShellBash:
+		return bashCompletion, nil
+	case gobin.ShellZsh:
+		return zshCompletion, nil
+	case gobin.ShellFish:
+		return fishCompletion, nil
+	case gobin.ShellPowerShell, "pwsh":
+		return powerShellCompletion, nil
+	}
+	return "", errors.New(fmt.Sprintf("unsupported shell “%s” for completion", shell))
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"flag"
 	"fmt"
@@ -10,55 +11,62 @@ import (
 	"github.com/knaka/gobin/log"
 	"github.com/knaka/gobin/minlib"
 	"github.com/knaka/gobin/vlog"
+	"io"
 	stdlog "log"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"text/template"
 )
 
 func main() {
 	Debugger()
-	shouldNotSwitch := false
-outer:
-	for {
-		if len(os.Args) <= 1 {
-			break
-		}
-		switch os.Args[1] {
-		case "--no-switch":
-			shouldNotSwitch = true
-		case "--silent":
-			log.SetSilent(true)
-		case "--verbose":
-			vlog.SetVerbose(true)
-		default:
-			break outer
+	var err error
+	if !filepath.IsAbs(os.Args[0]) {
+		// If the command is called without an absolute path, search for the command in the $PATH.
+		os.Args[0] = V(exec.LookPath(os.Args[0]))
+	}
+	cmdPath := filepath.Clean(V(filepath.Abs(os.Args[0])))
+	_, globalGoBinPath := V2(minlib.GlobalConfDirPath())
+	cmdBase := removeExeExt(filepath.Base(os.Args[0]))
+	calledAsGobin := cmdBase == minlib.GobinCmdBase || strings.HasPrefix(cmdBase, minlib.GobinCmdBase+"@")
+
+	// The global options are parsed only when called as gobin, not to take the arguments for the tools run through the shims.
+	opts := &globalOptsT{}
+	args := os.Args[1:]
+	if calledAsGobin {
+		globalFlags := newFlagSet("gobin")
+		defineGlobalFlags(globalFlags, opts)
+		if err_ := globalFlags.Parse(args); err_ != nil {
+			if errors.Is(err_, flag.ErrHelp) {
+				printUsage(os.Stdout)
+				os.Exit(0)
+			}
+			V0(fmt.Fprintf(os.Stderr, "gobin: %v\n", err_))
+			printUsage(os.Stderr)
+			os.Exit(2)
 		}
-		os.Args = append(os.Args[:1], os.Args[2:]...)
+		args = globalFlags.Args()
 	}
-	var err error
+	vlog.SetVerbose(opts.verbose)
+	log.SetSilent(opts.silent)
 	if os.Getenv("GOBIN_SILENT") != "" {
 		log.SetSilent(true)
 	}
 	if os.Getenv("GOBIN_VERBOSE") != "" {
 		vlog.SetVerbose(true)
 	}
-	if !filepath.IsAbs(os.Args[0]) {
-		// If the command is called without an absolute path, search for the command in the $PATH.
-		os.Args[0] = V(exec.LookPath(os.Args[0]))
-	}
-	cmdPath := filepath.Clean(V(filepath.Abs(os.Args[0])))
-	_, globalGoBinPath := V2(minlib.GlobalConfDirPath())
 
 	// Switch to the installed gobin command of the appropriate version.
-	if os.Getenv("NOSWITCH") == "" && !shouldNotSwitch {
+	if os.Getenv("NOSWITCH") == "" && !opts.noSwitch {
 		cmdGobinPath, err_ := minlib.EnsureGobinCmdInstalled(V(fsutils.IsSubDir(cmdPath, globalGoBinPath)))
 		if err_ != nil {
-			stdlog.Fatalf("Error 3c4804d: %+v", err)
+			stdlog.Fatalf("Error 3c4804d: %+v", err_)
 		}
-		if V(fsutils.CanonPath(V(os.Executable()))) != V(fsutils.CanonPath(cmdGobinPath)) {
+		if !sameFile(V(os.Executable()), cmdGobinPath) {
 			vlog.Printf("Switching to the installed gobin command: %s\n", cmdGobinPath)
 			cmd := exec.Command(cmdGobinPath)
 			// Save the original command path.
@@ -70,17 +78,15 @@ outer:
 			if err_ == nil {
 				os.Exit(0)
 			}
-			var errExec *exec.ExitError
-			if errors.As(err_, &errExec) && errExec != nil {
-				os.Exit(errExec.ExitCode())
+			if code, ok := exitCode(err_); ok {
+				os.Exit(code)
 			}
 			stdlog.Fatalf("Error 7d70a88: %+v", err_)
 		}
 	}
 
 	// If called as a symlink to the locally installed program, run the program of the appropriate version.
-	cmdBase := removeExeExt(filepath.Base(os.Args[0]))
-	if !(cmdBase == minlib.GobinCmdBase || strings.HasPrefix(cmdBase, minlib.GobinCmdBase+"@")) {
+	if !calledAsGobin {
 		if filepath.Base(filepath.Dir(cmdPath)) == minlib.GobinDirBase ||
 			V(fsutils.IsSubDir(filepath.Dir(cmdPath), globalGoBinPath)) {
 			opts := []gobin.Option{}
@@ -92,15 +98,35 @@ outer:
 			if err_ != nil {
 				stdlog.Fatalf("Error 078a110: %+v", err_)
 			}
+			// Run by “gobin generate”, the directive is skipped if its inputs are not changed.
+			directive, err_ := gobin.GenerateDirectiveOf(targetCmdPath, os.Args[1:])
+			if err_ != nil {
+				stdlog.Fatalf("Error 41c7e0b: %+v", err_)
+			}
+			if directive != nil {
+				// An unreadable cache makes the directive stale rather than failing the generation.
+				fresh, err_ := directive.IsFresh()
+				if err_ != nil {
+					log.Printf("Warning: running %s because the cache of go generate is unreadable: %v\n", cmdBase, err_)
+				}
+				if fresh {
+					log.Printf("Skipping %s whose inputs are not changed\n", cmdBase)
+					os.Exit(0)
+				}
+			}
 			cmd, err_ := minlib.Command(targetCmdPath)
 			cmd.Args = os.Args
 			err_ = cmd.Run()
 			if err_ == nil {
+				if directive != nil {
+					if err_ := directive.Record(); err_ != nil {
+						log.Printf("Warning: failed to record the inputs of %s for go generate: %v\n", cmdBase, err_)
+					}
+				}
 				os.Exit(0)
 			}
-			var execErr *exec.ExitError
-			if errors.As(err, &execErr) && execErr != nil {
-				os.Exit(execErr.ExitCode())
+			if code, ok := exitCode(err_); ok {
+				os.Exit(code)
 			}
 			stdlog.Fatalf("Error 608a109: %+v", err_)
 		}
@@ -108,90 +134,120 @@ outer:
 
 	// Continue as gobin command.
 
-	verbose := flag.Bool("v", false, "Verbose output.")
-	silent := flag.Bool("s", false, "Silent output.")
-	shouldHelp := flag.Bool("h", false, "Show help.")
-	global := flag.Bool("g", false, "Install globally.")
-	flag.Usage = func() {
-		V0(fmt.Fprintln(os.Stderr, `Usage: gobin [options] <command> [<args>...]
+	if opts.help {
+		printUsage(os.Stdout)
+		os.Exit(0)
+	}
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		os.Exit(1)
+	}
+	subCmd := lookupSubCmd(args[0])
+	if subCmd == nil {
+		V0(fmt.Fprintf(os.Stderr, "Unknown subcommand: %s\n", args[0]))
+		printUsage(os.Stderr)
+		os.Exit(1)
+	}
+	err = subCmd.exec(args[1:], opts)
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if code, ok := exitCode(err); ok {
+		os.Exit(code)
+	}
+	if err != nil {
+		stdlog.Fatalf("Error beba31d: %+v", err)
+	}
+}
 
-Options:`))
-		flag.PrintDefaults()
-		V0(fmt.Fprintln(os.Stderr))
-		V0(fmt.Fprintln(os.Stderr, `Commands:
-  list                    List packages listed in the manifest file “Gobinfile”.
-  run <name> [<args>...]  Run the specified program package.
-  install [<name>...]     Install the specified package(s).
-  update [<name>...]      Update the specified “@latest” program package(s). If no package is specified, update all packages.
+// defaultShellName returns the name of the user's shell if supported by “env”, or “sh” otherwise.
+func defaultShellName() string {
+	name := removeExeExt(filepath.Base(gobin.DefaultShell()))
+	switch name {
+	case gobin.ShellBash, gobin.ShellZsh, gobin.ShellFish, gobin.ShellPowerShell:
+		return name
+	case "pwsh":
+		return gobin.ShellPowerShell
+	}
+	return gobin.ShellSh
+}
 
-Environment variables:
-  NOSWITCH                If set, not switch to the locally installed (in “.gobin” directory) gobin command.`))
+// printToolHelp prints the help of the tool.
+func printToolHelp(entry *gobin.ListEntry) {
+	fmt.Printf("%s\n", path.Base(entry.Pkg))
+	if entry.Description != "" {
+		fmt.Printf("    %s\n", entry.Description)
 	}
-	flag.Parse()
-	if !filepath.IsAbs(os.Args[0]) {
-		os.Args[0] = V(exec.LookPath(os.Args[0]))
+	fmt.Printf("\n")
+	fmt.Printf("  Package:         %s\n", entry.Pkg)
+	fmt.Printf("  Version:         %s\n", entry.Version)
+	fmt.Printf("  Locked version:  %s\n", Ternary(entry.LockedVersion == "latest", "undefined", entry.LockedVersion))
+	if entry.Tags != "" {
+		fmt.Printf("  Tags:            %s\n", entry.Tags)
 	}
-	vlog.SetVerbose(*verbose)
-	log.SetSilent(*silent)
-	if os.Getenv("GOBIN_SILENT") != "" {
-		log.SetSilent(true)
+	if len(entry.Groups) > 0 {
+		fmt.Printf("  Groups:          %s\n", strings.Join(entry.Groups, ","))
 	}
-	if os.Getenv("GOBIN_VERBOSE") != "" {
-		vlog.SetVerbose(true)
+	if len(entry.Requires) > 0 {
+		fmt.Printf("  Requires:        %s\n", strings.Join(entry.Requires, ", "))
 	}
-	if *shouldHelp {
-		flag.Usage()
-		os.Exit(0)
+	fmt.Printf("  Install path:    %s%s\n", Elvis(entry.BinPath, "undefined"), Ternary(entry.Cached, "", " (not installed)"))
+	fmt.Printf("  Version from:    %s\n", entry.Origin)
+	fmt.Printf("  Defined in:      %s\n", entry.Source)
+}
+
+// printListEntries prints the entries with the template, one per line. The entries for which the template fails are reported with their packages, and the others are printed.
+func printListEntries(writer io.Writer, tmpl *template.Template, entries []*gobin.ListEntry) error {
+	var errs []error
+	for _, entry := range entries {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, entry); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Pkg, err))
+			continue
+		}
+		_, _ = fmt.Fprintf(writer, "%s\n", buf.String())
 	}
-	if flag.NArg() == 0 {
-		flag.Usage()
-		os.Exit(1)
+	return errors.Join(errs...)
+}
+
+// depNodeLabel returns the label of the node in the tree of the requirements.
+func depNodeLabel(node *gobin.DepNode) string {
+	if node.Pkg == "" {
+		return node.Name + " (" + Elvis(node.Path, "not found") + ")"
 	}
-	subCmd := flag.Arg(0)
-	subArgs := flag.Args()[1:]
-	switch subCmd {
-	case "run":
-		_, err = gobin.RunEx(subArgs,
-			gobin.WithStdin(os.Stdin),
-			gobin.WithStdout(os.Stdout),
-			gobin.WithStderr(os.Stderr),
-			gobin.Global(*global),
-		)
-	case "install":
-		_, err = gobin.InstallEx(subArgs,
-			gobin.Global(*global),
-		)
-	case "update":
-		err = gobin.UpdateEx(subArgs,
-			gobin.Global(*global),
-		)
-	case "list":
-		l, err_ := gobin.List(*global)
-		if err_ != nil {
-			stdlog.Fatalf("Error 6eaee66: %+v", err_)
-		}
-		for _, entry := range l {
-			fmt.Printf("%s@%s -> %s\n",
-				entry.Pkg,
-				entry.Version,
-				Ternary(entry.LockedVersion == "latest",
-					"undefined",
-					entry.LockedVersion,
-				),
-			)
+	label := node.Pkg + "@" + node.Version
+	if node.Name != node.Pkg {
+		label = node.Name + " (" + label + ")"
+	}
+	return label
+}
+
+// printDepTree prints the requirements of the node below it with the prefix of the lines.
+func printDepTree(writer io.Writer, node *gobin.DepNode, prefix string) {
+	for i, dep := range node.Requires {
+		branch, indent := "├── ", "│   "
+		if i == len(node.Requires)-1 {
+			branch, indent = "└── ", "    "
 		}
-	case "help":
-		flag.Usage()
-		os.Exit(0)
-	default:
-		V0(fmt.Fprintf(os.Stderr, "Unknown subcommand: %s\n", subCmd))
-		flag.Usage()
-		os.Exit(1)
+		_, _ = fmt.Fprintf(writer, "%s%s%s\n", prefix, branch, depNodeLabel(dep))
+		printDepTree(writer, dep, prefix+indent)
 	}
+}
+
+// relPath returns the path relative to the base directory if possible, or the path itself otherwise.
+func relPath(base string, path string) string {
+	rel, err := filepath.Rel(base, path)
 	if err != nil {
-		stdlog.Fatalf("Error beba31d: %+v", err)
+		return path
 	}
-	return
+	return rel
+}
+
+// sameFile returns true if both paths point to the same file, through a symlink, as a hard link or as the file itself.
+func sameFile(path1 string, path2 string) bool {
+	info1, err1 := os.Stat(path1)
+	info2, err2 := os.Stat(path2)
+	return err1 == nil && err2 == nil && os.SameFile(info1, info2)
 }
 
 func removeExeExt(path string) string {
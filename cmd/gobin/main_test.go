@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+	"time"
+
+	. "github.com/knaka/go-utils"
+	"github.com/knaka/gobin"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_subCmdExec(t *testing.T) {
+	var got []string
+	subCmd := &subCmdT{
+		name:    "run",
+		minArgs: 1,
+		maxArgs: -1,
+		define: func(flags *flagSetT, _ *globalOptsT) func(args []string) error {
+			verbose := false
+			flags.boolVar(&verbose, "verbose", "v", "Verbose output.")
+			return func(args []string) error {
+				got = append([]string{Ternary(verbose, "verbose", "quiet")}, args...)
+				return nil
+			}
+		},
+	}
+	V0(subCmd.exec([]string{"-v", "stringer", "-type", "Pill", "--", "-v"}, &globalOptsT{}))
+	assert.Equal(t, []string{"verbose", "stringer", "-type", "Pill", "--", "-v"}, got)
+	V0(subCmd.exec([]string{"--", "-v"}, &globalOptsT{}))
+	assert.Equal(t, []string{"quiet", "-v"}, got)
+	assert.ErrorIs(t, subCmd.exec([]string{"--bogus"}, &globalOptsT{}), errUsage)
+	assert.ErrorIs(t, subCmd.exec(nil, &globalOptsT{}), errUsage)
+}
+
+func Test_complete(t *testing.T) {
+	assert.Equal(t, []string{"shell", "shims"}, V(complete([]string{"-v", "sh"})))
+	assert.Equal(t, []string{"--global"}, V(complete([]string{"--gl"})))
+	assert.Equal(t, []string{"-G", "--group", "--strategy"}, V(complete([]string{"shims", "-"})))
+	assert.Equal(t, []string{"hardlink"}, V(complete([]string{"shims", "--strategy", "h"})))
+	assert.Equal(t, []string{"--shell=fish"}, V(complete([]string{"env", "--shell=f"})))
+	assert.Equal(t, []string{"toml", "tools-go"}, V(complete([]string{"migrate", "t"})))
+	assert.Empty(t, V(complete([]string{"migrate", "toml", ""})))
+	assert.Empty(t, V(complete([]string{completeCmdBase, ""})))
+}
+
+func Test_listFormat(t *testing.T) {
+	// A malformed template fails with an error instead of panicking, before listing.
+	err := lookupSubCmd("list").exec([]string{"-f", "{{.Pkg"}, &globalOptsT{})
+	assert.ErrorContains(t, err, "unclosed action")
+
+	// The templates which fail only on some entries print the others.
+	entries := []*gobin.ListEntry{
+		{Pkg: "example.com/cmd/foo", Requires: []string{"bar"}, ModTime: P(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))},
+		{Pkg: "example.com/cmd/bar"},
+	}
+	var output bytes.Buffer
+	V0(printListEntries(&output, template.Must(template.New("list").Parse(`{{.Pkg}} {{if .ModTime}}{{.ModTime.Format "2006"}}{{end}}`)), entries))
+	assert.Equal(t, "example.com/cmd/foo 2024\nexample.com/cmd/bar \n", output.String())
+	output.Reset()
+	err = printListEntries(&output, template.Must(template.New("list").Parse(`{{index .Requires 0}}`)), entries)
+	assert.Equal(t, "bar\n", output.String())
+	assert.ErrorContains(t, err, "example.com/cmd/bar: ")
+	assert.NotContains(t, err.Error(), "example.com/cmd/foo")
+	output.Reset()
+	err = printListEntries(&output, template.Must(template.New("list").Parse(`{{.NoSuchField}}`)), entries)
+	assert.ErrorContains(t, err, "can't evaluate field NoSuchField")
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// flagSpec describes a flag which has a long name and optionally a short one.
+type flagSpec struct {
+	long  string
+	short string
+	// arg is the name of the value in the usage, or empty for a boolean flag.
+	arg   string
+	usage string
+	// values are the completion candidates of the value.
+	values []string
+}
+
+// names returns the names of the flag with the leading dashes.
+func (spec *flagSpec) names() (names []string) {
+	if spec.short != "" {
+		names = append(names, "-"+spec.short)
+	}
+	return append(names, "--"+spec.long)
+}
+
+// flagSetT is the flag set which registers each flag under its long and short names and prints them in pairs in the usage.
+type flagSetT struct {
+	*flag.FlagSet
+	specs []*flagSpec
+}
+
+func newFlagSet(name string) *flagSetT {
+	flags := &flagSetT{FlagSet: flag.NewFlagSet(name, flag.ContinueOnError)}
+	// Errors are printed with the usage by the caller.
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+func (flags *flagSetT) boolVar(p *bool, long string, short string, usage string) {
+	flags.specs = append(flags.specs, &flagSpec{long: long, short: short, usage: usage})
+	flags.BoolVar(p, long, *p, usage)
+	if short != "" {
+		flags.BoolVar(p, short, *p, usage)
+	}
+}
+
+func (flags *flagSetT) stringVar(p *string, long string, short string, arg string, usage string, values ...string) {
+	flags.specs = append(flags.specs, &flagSpec{long: long, short: short, arg: arg, usage: usage, values: values})
+	flags.StringVar(p, long, *p, usage)
+	if short != "" {
+		flags.StringVar(p, short, *p, usage)
+	}
+}
+
+// lookupSpec returns the spec of the flag in the form of “-x”, “--xxx” or “--xxx=value”.
+func (flags *flagSetT) lookupSpec(arg string) *flagSpec {
+	name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+	for _, spec := range flags.specs {
+		if name == spec.long || (name == spec.short && spec.short != "") {
+			return spec
+		}
+	}
+	return nil
+}
+
+// printDefaults prints the flags in the usage.
+func (flags *flagSetT) printDefaults(writer io.Writer) {
+	var heads []string
+	width := 0
+	for _, spec := range flags.specs {
+		head := strings.Join(spec.names(), ", ")
+		if spec.short == "" {
+			head = "    " + head
+		}
+		if spec.arg != "" {
+			head += " <" + spec.arg + ">"
+		}
+		heads = append(heads, head)
+		width = max(width, len(head))
+	}
+	for i, spec := range flags.specs {
+		_, _ = fmt.Fprintf(writer, "  %-*s  %s\n", width, heads[i], spec.usage)
+	}
+}
+
+// globalOptsT holds the global options which precede the subcommand.
+type globalOptsT struct {
+	verbose  bool
+	silent   bool
+	help     bool
+	global   bool
+	noSwitch bool
+}
+
+// defineGlobalFlags defines the global options on the flag set.
+func defineGlobalFlags(flags *flagSetT, opts *globalOptsT) {
+	flags.boolVar(&opts.verbose, "verbose", "v", "Verbose output.")
+	flags.boolVar(&opts.silent, "silent", "s", "Silent output.")
+	flags.boolVar(&opts.help, "help", "h", "Show help.")
+	flags.boolVar(&opts.global, "global", "g", "Use the global manifest in the home directory and install into $GOBIN.")
+	flags.boolVar(&opts.noSwitch, "no-switch", "", "Do not switch to the locally installed (in “.gobin” directory) gobin command.")
+}
+
+// subCmdT is a subcommand of gobin.
+type subCmdT struct {
+	name string
+	// args is the synopsis of the positional arguments in the usage.
+	args    string
+	summary string
+	// hidden subcommands are not shown in the usage nor completed.
+	hidden bool
+	// minArgs and maxArgs limit the number of the positional arguments. Negative maxArgs means no limit.
+	minArgs int
+	maxArgs int
+	// argValues are the completion candidates of the first positional argument.
+	argValues []string
+	// rawArgs subcommands take all the arguments as positional ones without parsing flags.
+	rawArgs bool
+	// toolArgs is the number of the leading positional arguments which are tools, or -1 for all, for the completion.
+	toolArgs int
+	// define defines the flags of the subcommand and returns the function which runs it with the positional arguments.
+	define func(flags *flagSetT, opts *globalOptsT) func(args []string) error
+}
+
+// errUsage is returned when the arguments do not match the usage.
+var errUsage = errors.New("invalid arguments")
+
+// flagSet returns the flag set of the subcommand with its flags defined, and the function to run it.
+func (subCmd *subCmdT) flagSet(opts *globalOptsT) (flags *flagSetT, run func(args []string) error) {
+	flags = newFlagSet(subCmd.name)
+	if subCmd.define != nil {
+		run = subCmd.define(flags, opts)
+	}
+	return
+}
+
+// printUsage prints the usage of the subcommand generated from its definition.
+func (subCmd *subCmdT) printUsage(writer io.Writer, flags *flagSetT) {
+	synopsis := "gobin " + subCmd.name
+	if len(flags.specs) > 0 {
+		synopsis += " [options]"
+	}
+	if subCmd.args != "" {
+		synopsis += " " + subCmd.args
+	}
+	_, _ = fmt.Fprintf(writer, "Usage: %s\n\n%s\n", synopsis, subCmd.summary)
+	if len(flags.specs) > 0 {
+		_, _ = fmt.Fprintf(writer, "\nOptions:\n")
+		flags.printDefaults(writer)
+	}
+}
+
+// exec parses the arguments of the subcommand and runs it. The flags are parsed only up to the first positional argument or “--”, so that the arguments for the tools are never taken by gobin.
+func (subCmd *subCmdT) exec(args []string, opts *globalOptsT) (err error) {
+	flags, run := subCmd.flagSet(opts)
+	if subCmd.rawArgs {
+		return run(args)
+	}
+	if err = flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			subCmd.printUsage(os.Stdout, flags)
+			return nil
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "gobin %s: %v\n", subCmd.name, err)
+		subCmd.printUsage(os.Stderr, flags)
+		return errUsage
+	}
+	if flags.NArg() < subCmd.minArgs || (subCmd.maxArgs >= 0 && flags.NArg() > subCmd.maxArgs) {
+		subCmd.printUsage(os.Stderr, flags)
+		return errUsage
+	}
+	return run(flags.Args())
+}
+
+// lookupSubCmd returns the subcommand of the name, or nil if not found.
+func lookupSubCmd(name string) *subCmdT {
+	idx := slices.IndexFunc(subCmds, func(subCmd *subCmdT) bool { return subCmd.name == name })
+	if idx < 0 {
+		return nil
+	}
+	return subCmds[idx]
+}
+
+// printUsage prints the usage of gobin with the global options and the summaries of the subcommands.
+func printUsage(writer io.Writer) {
+	_, _ = fmt.Fprintf(writer, "Usage: gobin [options] <command> [<args>...]\n\nOptions:\n")
+	flags := newFlagSet("gobin")
+	defineGlobalFlags(flags, &globalOptsT{})
+	flags.printDefaults(writer)
+	_, _ = fmt.Fprintf(writer, "\nCommands:\n")
+	width := 0
+	for _, subCmd := range subCmds {
+		if !subCmd.hidden {
+			width = max(width, len(subCmd.name))
+		}
+	}
+	for _, subCmd := range subCmds {
+		if subCmd.hidden {
+			continue
+		}
+		_, _ = fmt.Fprintf(writer, "  %-*s  %s\n", width, subCmd.name, strings.SplitN(subCmd.summary, "\n", 2)[0])
+	}
+	_, _ = fmt.Fprintf(writer, `
+Run “gobin <command> --help” for the options and the arguments of the command.
+
+Environment variables:
+  NOSWITCH  If set, not switch to the locally installed (in “.gobin” directory) gobin command.
+`)
+}
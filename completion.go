@@ -0,0 +1,53 @@
+package gobin
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	. "github.com/knaka/go-utils"
+)
+
+// CompleteToolsEx returns the names which start with the prefix and which the commands like “run” and “install” accept as a tool: the base names and the package paths of the manifest entries, and the package paths of the main packages whose modules go.mod requires, which are the ones of the “tool” directives and of the blank imports in the “tools.go” files. The names are checked against the same lookup as install, so a base name shared by several entries is offered only for the entry it resolves to.
+func CompleteToolsEx(prefix string, opts ...Option) (candidates []string, err error) {
+	return (&Installer{}).CompleteToolsEx(prefix, opts...)
+}
+
+// CompleteToolsEx returns the names of the tools which start with the prefix.
+func (installer *Installer) CompleteToolsEx(prefix string, opts ...Option) (candidates []string, err error) {
+	defer Catch(&err)
+	params := V(installer.params(opts))
+	confDirPath, _ := V2(params.dirs())
+	return completeTools(prefix, params, confDirPath)
+}
+
+func completeTools(prefix string, params *installParams, confDirPath string) (candidates []string, err error) {
+	defer Catch(&err)
+	manifest := V(parseManifest(confDirPath))
+	seen := map[string]bool{}
+	add := func(candidate string) {
+		if !seen[candidate] && strings.HasPrefix(candidate, prefix) {
+			seen[candidate] = true
+			candidates = append(candidates, candidate)
+		}
+	}
+	for _, entry := range manifest.Entries() {
+		if !entry.inGroups(params.groups) {
+			continue
+		}
+		for _, name := range []string{path.Base(entry.Pkg), entry.Pkg} {
+			if manifest.lookup(name) == entry {
+				add(name)
+			}
+		}
+	}
+	if params.optGlobal == nil || !*params.optGlobal {
+		if goModDef := V(parseGoMod(confDirPath)); goModDef != nil {
+			for _, pkg := range V(goModDef.toolPkgsOf(confDirPath)) {
+				add(pkg)
+			}
+		}
+	}
+	sort.Strings(candidates)
+	return
+}
@@ -0,0 +1,77 @@
+package gobin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// WithContext sets the context which kills the go commands, the download of the Go SDK and the command run by RunEx when done. The errors caused by the context wrap the error of the context, so that errors.Is(err, context.Canceled) holds.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithContext(ctx context.Context) Option {
+	return func(params *installParams) (err error) {
+		if ctx == nil {
+			return errors.New("nil context")
+		}
+		params.ctx = ctx
+		return
+	}
+}
+
+// contextErr returns the error wrapped with the error of the context if the context is done, which is the cause of the failure then.
+func contextErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+	return err
+}
+
+// InstallContext is InstallEx with the context.
+//
+//goland:noinspection GoUnusedExportedFunction
+func InstallContext(ctx context.Context, patterns []string, opts ...Option) (cmdPath string, err error) {
+	return (&Installer{}).InstallContext(ctx, patterns, opts...)
+}
+
+// CommandContext is CommandEx with the context, which also kills the returned command when done.
+//
+//goland:noinspection GoUnusedExportedFunction
+func CommandContext(ctx context.Context, args []string, opts ...Option) (cmd *exec.Cmd, err error) {
+	return (&Installer{}).CommandContext(ctx, args, opts...)
+}
+
+// RunContext is RunEx with the context.
+//
+//goland:noinspection GoUnusedExportedFunction
+func RunContext(ctx context.Context, args []string, opts ...Option) (errExit *exec.ExitError, err error) {
+	return (&Installer{}).RunContext(ctx, args, opts...)
+}
+
+// UpdateContext is UpdateEx with the context.
+//
+//goland:noinspection GoUnusedExportedFunction
+func UpdateContext(ctx context.Context, patterns []string, opts ...Option) (err error) {
+	return (&Installer{}).UpdateContext(ctx, patterns, opts...)
+}
+
+// InstallContext is InstallEx with the context.
+func (installer *Installer) InstallContext(ctx context.Context, patterns []string, opts ...Option) (cmdPath string, err error) {
+	return installer.InstallEx(patterns, append([]Option{WithContext(ctx)}, opts...)...)
+}
+
+// CommandContext is CommandEx with the context.
+func (installer *Installer) CommandContext(ctx context.Context, args []string, opts ...Option) (cmd *exec.Cmd, err error) {
+	return installer.CommandEx(args, append([]Option{WithContext(ctx)}, opts...)...)
+}
+
+// RunContext is RunEx with the context.
+func (installer *Installer) RunContext(ctx context.Context, args []string, opts ...Option) (errExit *exec.ExitError, err error) {
+	return installer.RunEx(args, append([]Option{WithContext(ctx)}, opts...)...)
+}
+
+// UpdateContext is UpdateEx with the context.
+func (installer *Installer) UpdateContext(ctx context.Context, patterns []string, opts ...Option) (err error) {
+	return installer.UpdateEx(patterns, append([]Option{WithContext(ctx)}, opts...)...)
+}
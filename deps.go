@@ -0,0 +1,275 @@
+package gobin
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	. "github.com/knaka/go-utils"
+	"golang.org/x/mod/module"
+)
+
+// depGraphT is the graph of the commands where a manifest entry depends on the commands of its “requires” option.
+type depGraphT struct {
+	manifest *manifestT
+	goModDef *goModDefT
+}
+
+// splitRequire splits the command in the “requires” option, e.g. “stringer@v0.23.0”, into the name and the version, which is empty if not specified.
+func splitRequire(require string) (name string, version string) {
+	name, version, _ = strings.Cut(require, "@")
+	return
+}
+
+// node returns the key of the node of the target and its manifest entry. The version of the target required in go.mod takes precedence as in install, and such a target has no entry. The key is the package of the entry, or the name of the target if not in the manifest.
+func (graph *depGraphT) node(target string) (key string, entry *maniEntry) {
+	name, _ := splitRequire(target)
+	if graph.goModRequirement(name) != nil {
+		return name, nil
+	}
+	if entry = graph.manifest.lookup(name); entry != nil {
+		return entry.Pkg, entry
+	}
+	return name, nil
+}
+
+// goModRequirement returns the module required in go.mod which provides the package, or nil.
+func (graph *depGraphT) goModRequirement(pkg string) *module.Version {
+	if graph.goModDef == nil {
+		return nil
+	}
+	return graph.goModDef.requiredModuleByPkg(pkg)
+}
+
+// deps returns the targets which the target requires. An entry not applicable to the platform requires nothing because it is not installed.
+func (graph *depGraphT) deps(target string) []string {
+	_, entry := graph.node(target)
+	if entry == nil || !entry.Applicable {
+		return nil
+	}
+	return entry.Requires
+}
+
+// sort returns the targets and the ones they require transitively, each once, in the order to install, where every target follows the ones it requires. It fails if the requirements make a cycle.
+func (graph *depGraphT) sort(targets []string) (order []string, err error) {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	states := map[string]int{}
+	var stack []string
+	var visit func(target string) error
+	visit = func(target string) error {
+		key, _ := graph.node(target)
+		switch states[key] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := append(slices.Clone(stack[slices.Index(stack, key):]), key)
+			return errors.New(fmt.Sprintf("“requires” makes a cycle: %s", strings.Join(cycle, " -> ")))
+		}
+		states[key] = visiting
+		stack = append(stack, key)
+		for _, dep := range graph.deps(target) {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		states[key] = visited
+		order = append(order, target)
+		return nil
+	}
+	for _, target := range targets {
+		if err = visit(target); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// checkCycles returns an error if the requirements of any entry make a cycle.
+func (graph *depGraphT) checkCycles() (err error) {
+	var pkgs []string
+	for _, entry := range graph.manifest.Entries() {
+		pkgs = append(pkgs, entry.Pkg)
+	}
+	_, err = graph.sort(pkgs)
+	return
+}
+
+// requiredVersions returns the versions which the “requires” options specify, by the keys of the nodes. It fails if a version conflicts with another one required for the same command, with the version of its entry, or with the version required in go.mod, or if the command is neither defined in the manifest nor required in go.mod.
+func (graph *depGraphT) requiredVersions() (versions map[string]string, err error) {
+	versions = map[string]string{}
+	requiredBy := map[string]string{}
+	var errs []error
+	for _, entry := range graph.manifest.Entries() {
+		for _, require := range graph.deps(entry.Pkg) {
+			name, version := splitRequire(require)
+			if version == "" {
+				continue
+			}
+			key, reqEntry := graph.node(require)
+			if prevVersion, ok := versions[key]; ok {
+				if prevVersion != version {
+					errs = append(errs, errors.New(fmt.Sprintf("%s requires %s@%s, which conflicts with %s@%s required by %s", entry.Pkg, name, version, name, prevVersion, requiredBy[key])))
+				}
+				continue
+			}
+			versions[key], requiredBy[key] = version, entry.Pkg
+			if reqEntry != nil {
+				if reqEntry.LockedVersion != latestVer && reqEntry.LockedVersion != version {
+					errs = append(errs, errors.New(fmt.Sprintf("%s requires %s@%s, which conflicts with the version %s of %s in the manifest", entry.Pkg, name, version, reqEntry.LockedVersion, reqEntry.Pkg)))
+				}
+			} else if reqMod := graph.goModRequirement(key); reqMod != nil {
+				if reqMod.Version != version {
+					errs = append(errs, errors.New(fmt.Sprintf("%s requires %s@%s, which conflicts with the version %s of %s in go.mod", entry.Pkg, name, version, reqMod.Version, reqMod.Path)))
+				}
+			} else {
+				// The command looked up in PATH is used whatever its version is.
+				errs = append(errs, errors.New(fmt.Sprintf("%s requires %s@%s, but the version cannot be specified for %s, which is neither defined in the manifest nor required in go.mod", entry.Pkg, name, version, name)))
+			}
+		}
+	}
+	return versions, errors.Join(errs...)
+}
+
+// rootTargets returns the packages of the entries in the groups which no other entry requires.
+func (graph *depGraphT) rootTargets(groups []string) (roots []string) {
+	required := map[string]bool{}
+	for _, entry := range graph.manifest.Entries() {
+		for _, dep := range graph.deps(entry.Pkg) {
+			key, _ := graph.node(dep)
+			required[key] = true
+		}
+	}
+	for _, entry := range graph.manifest.Entries() {
+		if entry.inGroups(groups) && !required[entry.Pkg] {
+			roots = append(roots, entry.Pkg)
+		}
+	}
+	return
+}
+
+// DepNode is a command in the tree of the requirements.
+type DepNode struct {
+	// Name is the command as written in the “requires” option, or the package at the root.
+	Name string
+	// Pkg is empty if the command is not defined.
+	Pkg string
+	// Version is the locked version, or the version required in go.mod.
+	Version string
+	// Path is the path of the executable found in PATH for the command which is not defined.
+	Path string `json:",omitempty"`
+	// Requires are the commands which the command requires.
+	Requires []*DepNode `json:",omitempty"`
+}
+
+// tree returns the tree of the requirements of the target, which has no cycle.
+func (graph *depGraphT) tree(target string) (node *DepNode) {
+	node = &DepNode{Name: target}
+	key, entry := graph.node(target)
+	if entry != nil {
+		node.Pkg, node.Version = entry.Pkg, entry.LockedVersion
+	} else if reqMod := graph.goModRequirement(key); reqMod != nil {
+		node.Pkg, node.Version = key, reqMod.Version
+	} else if cmdPath, err := exec.LookPath(key); err == nil {
+		node.Path = cmdPath
+	}
+	for _, dep := range graph.deps(target) {
+		node.Requires = append(node.Requires, graph.tree(dep))
+	}
+	return
+}
+
+// dependents returns the chains of the packages from the entries which nothing requires down to the target, through the “requires” options.
+func (graph *depGraphT) dependents(target string) (chains [][]string) {
+	key, _ := graph.node(target)
+	var walk func(key string, chain []string)
+	walk = func(key string, chain []string) {
+		chain = append([]string{key}, chain...)
+		found := false
+		for _, entry := range graph.manifest.Entries() {
+			if slices.ContainsFunc(graph.deps(entry.Pkg), func(dep string) bool {
+				depKey, _ := graph.node(dep)
+				return depKey == key
+			}) {
+				found = true
+				walk(entry.Pkg, chain)
+			}
+		}
+		if !found && len(chain) > 1 {
+			chains = append(chains, chain)
+		}
+	}
+	walk(key, nil)
+	return
+}
+
+// newDepGraph returns the graph of the manifest in the configuration directory, checked for cycles and conflicting versions.
+func newDepGraph(params *installParams, confDirPath string) (graph *depGraphT, err error) {
+	defer Catch(&err)
+	graph = &depGraphT{manifest: V(parseManifest(confDirPath, withPlatform(params.goos, params.goarch)))}
+	if params.optGlobal == nil || !*params.optGlobal {
+		graph.goModDef = V(parseGoMod(confDirPath))
+	}
+	V0(graph.checkCycles())
+	V(graph.requiredVersions())
+	return
+}
+
+// depTree returns the trees of the requirements of the targets, or of the entries in the groups which no other entry requires if no target is specified.
+func depTree(targets []string, params *installParams, confDirPath string) (roots []*DepNode, err error) {
+	defer Catch(&err)
+	graph := V(newDepGraph(params, confDirPath))
+	if len(targets) == 0 {
+		targets = graph.rootTargets(params.groups)
+	}
+	for _, target := range targets {
+		roots = append(roots, graph.tree(target))
+	}
+	return
+}
+
+// DepTreeEx returns the trees of the commands which the targets require through the “requires” options. Without targets, the roots are the entries which no other entry requires.
+//
+//goland:noinspection GoUnusedExportedFunction
+func DepTreeEx(targets []string, opts ...Option) (roots []*DepNode, err error) {
+	return (&Installer{}).DepTreeEx(targets, opts...)
+}
+
+// DepTreeEx returns the trees of the commands which the targets require through the “requires” options.
+func (installer *Installer) DepTreeEx(targets []string, opts ...Option) (roots []*DepNode, err error) {
+	defer Catch(&err)
+	params := V(installer.params(opts))
+	confDirPath, _ := V2(params.dirs())
+	return depTree(targets, params, confDirPath)
+}
+
+// why returns the chains of the packages which require the target.
+func why(target string, params *installParams, confDirPath string) (chains [][]string, err error) {
+	defer Catch(&err)
+	graph := V(newDepGraph(params, confDirPath))
+	chains = graph.dependents(target)
+	if _, entry := graph.node(target); entry == nil && len(chains) == 0 {
+		return nil, newKindError(ErrToolNotDefined, "command “%s” is not defined", target)
+	}
+	return
+}
+
+// WhyEx returns the chains of the packages from the entries which no other entry requires down to the target through the “requires” options, like “go mod why”. It returns no chain if nothing requires the target.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WhyEx(target string, opts ...Option) (chains [][]string, err error) {
+	return (&Installer{}).WhyEx(target, opts...)
+}
+
+// WhyEx returns the chains of the packages which require the target through the “requires” options.
+func (installer *Installer) WhyEx(target string, opts ...Option) (chains [][]string, err error) {
+	defer Catch(&err)
+	params := V(installer.params(opts))
+	confDirPath, _ := V2(params.dirs())
+	return why(target, params, confDirPath)
+}
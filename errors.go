@@ -0,0 +1,40 @@
+package gobin
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/knaka/gobin/minlib"
+)
+
+// The errors which the functions return wrapped, to be inspected with errors.Is.
+var (
+	// ErrToolNotDefined is the error of the command which is neither defined in the manifest nor required in go.mod.
+	ErrToolNotDefined = errors.New("command is not defined")
+	// ErrAmbiguousTool is the error of the base name shared by the packages of several manifest entries, which should be specified by the package path then.
+	ErrAmbiguousTool = errors.New("command is ambiguous")
+	// ErrNoConfig is the error when neither the manifest file nor go.mod is found in the directory and its ancestors.
+	ErrNoConfig = minlib.ErrNoConfig
+)
+
+// InstallError is the error of the build of the package by the go command, to be inspected with errors.As.
+type InstallError = minlib.InstallError
+
+// kindError is the error with its own message, which is the sentinel error of its kind for errors.Is.
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+// newKindError returns the error of the kind with the formatted message.
+func newKindError(kind error, format string, a ...any) error {
+	return &kindError{kind: kind, msg: fmt.Sprintf(format, a...)}
+}
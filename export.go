@@ -0,0 +1,220 @@
+package gobin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/knaka/go-utils"
+	"golang.org/x/mod/module"
+)
+
+// Formats to which ExportManifest exports the manifest.
+const (
+	// ExportFormatGoTool is the “require” and “tool” directives of go.mod, which Go 1.24 and later run with “go tool”.
+	ExportFormatGoTool = "go-tool"
+	// ExportFormatToolsGo is the “tools.go” file which imports the tools with the “tools” build tag, with the “go get” commands to pin the versions.
+	ExportFormatToolsGo = "tools-go"
+	// ExportFormatShell is the shell script which installs the tools with “go install”.
+	ExportFormatShell = "shell"
+)
+
+// exportEntries returns the entries to export with the versions resolved in the same order as install, from go.mod, then from the manifest and the lock file. It fails naming the entries whose versions are not locked yet.
+func exportEntries(params *installParams, confDirPath string) (entries []*maniEntry, err error) {
+	defer Catch(&err)
+	global := params.optGlobal != nil && *params.optGlobal
+	var goModDef *goModDefT
+	if !global {
+		goModDef = V(parseGoMod(confDirPath))
+	}
+	manifest := V(parseManifest(confDirPath, withPlatform(params.goos, params.goarch)))
+	var unlocked []string
+	for _, entry := range manifest.Entries() {
+		if !entry.inGroups(params.groups) {
+			continue
+		}
+		if !entry.Applicable {
+			params.logger().Printf("Skipping %s which is not applicable to the platform\n", entry.Pkg)
+			continue
+		}
+		if goModDef != nil {
+			if reqMod := goModDef.requiredModuleByPkg(entry.Pkg); reqMod != nil {
+				entry.LockedVersion = reqMod.Version
+			}
+		}
+		if entry.LockedVersion == latestVer {
+			unlocked = append(unlocked, entry.Pkg)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	// The exported files would not pin “latest”.
+	if len(unlocked) > 0 {
+		return nil, errors.New(fmt.Sprintf("the versions of %s are not locked yet; lock them with “gobin install” before exporting", strings.Join(unlocked, ", ")))
+	}
+	return
+}
+
+// goModCachePath returns the module cache directory.
+func goModCachePath(params *installParams) (dirPath string, err error) {
+	output, err := exec.CommandContext(params.ctx, params.goCmd(), "env", "GOMODCACHE").Output()
+	if err != nil {
+		return
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// resolveModule returns the module which provides the package at the version. The module is looked up in go.mod and in the module cache, where the installed tools are downloaded, before querying the module proxy.
+func resolveModule(pkg string, version string, goModDef *goModDefT, params *installParams) (mod *module.Version, err error) {
+	defer Catch(&err)
+	if goModDef != nil {
+		if reqMod := goModDef.requiredModuleByPkg(pkg); reqMod != nil {
+			return reqMod, nil
+		}
+	}
+	candidates := V(candidateModules(pkg))
+	if version != latestVer {
+		if modCachePath, err_ := goModCachePath(params); err_ == nil && modCachePath != "" {
+			for _, candidate := range candidates {
+				escapedPath, err_ := module.EscapePath(candidate)
+				if err_ != nil {
+					continue
+				}
+				escapedVer, err_ := module.EscapeVersion(version)
+				if err_ != nil {
+					continue
+				}
+				if _, err_ := os.Stat(filepath.Join(modCachePath, "cache", "download", escapedPath, "@v", escapedVer+".mod")); err_ == nil {
+					return &module.Version{Path: candidate, Version: version}, nil
+				}
+			}
+		}
+	}
+	for _, candidate := range candidates {
+		cmd := exec.CommandContext(params.ctx, params.goCmd(), "list", "-m", "--json", fmt.Sprintf("%s@%s", candidate, version))
+		cmd.Env = append(append(os.Environ(), params.goEnv...), "GO111MODULE=on")
+		output, err_ := cmd.Output()
+		if err_ != nil {
+			if params.ctx.Err() != nil {
+				Throw(contextErr(params.ctx, err_))
+			}
+			continue
+		}
+		V0(json.Unmarshal(output, &mod))
+		return
+	}
+	return nil, errors.New(fmt.Sprintf("no module found for “%s@%s”", pkg, version))
+}
+
+// warnDroppedOptions logs the build options of the entry which the format cannot represent.
+func warnDroppedOptions(entry *maniEntry, format string, params *installParams) {
+	if entry.Tags != "" || entry.Ldflags != "" || len(entry.Env) > 0 {
+		params.logger().Printf("The build options of %s are dropped in the %s format\n", entry.Pkg, format)
+	}
+}
+
+// writeGoTool writes the “require” and “tool” directives of the entries.
+func writeGoTool(writer io.Writer, entries []*maniEntry, goModDef *goModDefT, params *installParams) (err error) {
+	defer Catch(&err)
+	var mods []*module.Version
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		warnDroppedOptions(entry, ExportFormatGoTool, params)
+		mod := V(resolveModule(entry.Pkg, entry.LockedVersion, goModDef, params))
+		if !seen[mod.Path] {
+			seen[mod.Path] = true
+			mods = append(mods, mod)
+		}
+	}
+	V0(fmt.Fprintf(writer, "// Generated by “gobin export --format=%s”. Add to go.mod, which requires “go 1.24” or later.\n\nrequire (\n", ExportFormatGoTool))
+	for _, mod := range mods {
+		V0(fmt.Fprintf(writer, "\t%s %s\n", mod.Path, mod.Version))
+	}
+	V0(fmt.Fprintf(writer, ")\n\ntool (\n"))
+	for _, entry := range entries {
+		V0(fmt.Fprintf(writer, "\t%s\n", entry.Pkg))
+	}
+	V0(fmt.Fprintf(writer, ")\n"))
+	return
+}
+
+// writeToolsGo writes the “tools.go” file which imports the entries.
+func writeToolsGo(writer io.Writer, entries []*maniEntry, params *installParams) (err error) {
+	defer Catch(&err)
+	V0(fmt.Fprintf(writer, "//go:build %s\n\n// Code generated by “gobin export --format=%s”. Pin the versions with:\n//\n", toolsBuildTag, ExportFormatToolsGo))
+	for _, entry := range entries {
+		warnDroppedOptions(entry, ExportFormatToolsGo, params)
+		V0(fmt.Fprintf(writer, "//\tgo get %s@%s\n", entry.Pkg, entry.LockedVersion))
+	}
+	V0(fmt.Fprintf(writer, "\npackage tools\n\nimport (\n"))
+	for _, entry := range entries {
+		V0(fmt.Fprintf(writer, "\t_ %q\n", entry.Pkg))
+	}
+	V0(fmt.Fprintf(writer, ")\n"))
+	return
+}
+
+// writeShell writes the shell script which installs the entries with their build options.
+func writeShell(writer io.Writer, entries []*maniEntry) (err error) {
+	defer Catch(&err)
+	V0(fmt.Fprintf(writer, "#!/bin/sh\n# Generated by “gobin export --format=%s”.\nset -o errexit\n", ExportFormatShell))
+	for _, entry := range entries {
+		var words []string
+		if len(entry.Env) > 0 {
+			words = append(words, "env")
+			for _, env := range entry.Env {
+				words = append(words, quoteSh(env))
+			}
+		}
+		words = append(words, "go", "install")
+		if entry.Tags != "" {
+			words = append(words, "-tags", quoteSh(entry.Tags))
+		}
+		if entry.Ldflags != "" {
+			words = append(words, "-ldflags", quoteSh(entry.Ldflags))
+		}
+		words = append(words, entry.Pkg+"@"+entry.LockedVersion)
+		V0(fmt.Fprintln(writer, strings.Join(words, " ")))
+	}
+	return
+}
+
+// exportManifest writes the entries of the manifest in the configuration directory in the format.
+func exportManifest(format string, params *installParams, confDirPath string) (err error) {
+	defer Catch(&err)
+	entries := V(exportEntries(params, confDirPath))
+	switch format {
+	case ExportFormatGoTool:
+		var goModDef *goModDefT
+		if params.optGlobal == nil || !*params.optGlobal {
+			goModDef = V(parseGoMod(confDirPath))
+		}
+		return writeGoTool(params.stdout, entries, goModDef, params)
+	case ExportFormatToolsGo:
+		return writeToolsGo(params.stdout, entries, params)
+	case ExportFormatShell:
+		return writeShell(params.stdout, entries)
+	default:
+		return errors.New(fmt.Sprintf("unknown export format “%s”", format))
+	}
+}
+
+// ExportManifest writes the entries of the manifest with the locked versions to the standard output (WithStdout) in the format, so that the project can pin the tools without gobin. The build options are kept only in the shell format.
+//
+//goland:noinspection GoUnusedExportedFunction
+func ExportManifest(format string, opts ...Option) (err error) {
+	return (&Installer{}).ExportManifest(format, opts...)
+}
+
+// ExportManifest writes the entries of the manifest with the locked versions in the format.
+func (installer *Installer) ExportManifest(format string, opts ...Option) (err error) {
+	defer Catch(&err)
+	params := V(installer.params(opts))
+	confDirPath, _ := V2(params.dirs())
+	return exportManifest(format, params, confDirPath)
+}
@@ -0,0 +1,161 @@
+package gobin
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	. "github.com/knaka/go-utils"
+)
+
+// generateCacheEnv is the environment variable which GenerateCommandEx sets to the directory of the cache file, so that the shims run by “go generate” skip the directives whose inputs are not changed.
+const generateCacheEnv = "GOBIN_GENERATE_CACHE"
+
+// generateCacheBase is the name of the file which maps the directives to the hashes of their inputs at the last successful run.
+const generateCacheBase = "generate-cache.json"
+
+// Annotations which precede a “//go:generate” directive. They take space-separated glob patterns relative to the directory of the file.
+const (
+	generateInputPrefix  = "//go:generate_input"
+	generateOutputPrefix = "//go:generate_output"
+)
+
+// GenerateDirective is a “//go:generate” directive run by “gobin generate” with the “//go:generate_input” and “//go:generate_output” annotations.
+type GenerateDirective struct {
+	cachePath string
+	key       string
+	hash      string
+	dirPath   string
+	outputs   []string
+}
+
+// parseGenerateAnnotations returns the patterns of the annotations which directly precede the line (1-based) of the file.
+func parseGenerateAnnotations(filePath string, line int) (inputs []string, outputs []string, err error) {
+	defer Catch(&err)
+	file := V(os.Open(filePath))
+	defer (func() { Ignore(file.Close()) })()
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() && len(lines) < line-1 {
+		lines = append(lines, strings.TrimSpace(scanner.Text()))
+	}
+	V0(scanner.Err())
+	for i := len(lines) - 1; i >= 0; i-- {
+		if rest, ok := strings.CutPrefix(lines[i], generateInputPrefix); ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			inputs = append(strings.Fields(rest), inputs...)
+		} else if rest, ok := strings.CutPrefix(lines[i], generateOutputPrefix); ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			outputs = append(strings.Fields(rest), outputs...)
+		} else {
+			break
+		}
+	}
+	return
+}
+
+// hashGenerateInputs returns the hash of the binary name, which has the version and the digest of the build flags, the arguments, and the files which match the input patterns in the directory.
+func hashGenerateInputs(dirPath string, cmdPath string, args []string, inputs []string) (hash string, err error) {
+	defer Catch(&err)
+	hasher := sha256.New()
+	write := func(s string) {
+		V0(hasher.Write([]byte(s)))
+		V0(hasher.Write([]byte{0}))
+	}
+	write(filepath.Base(cmdPath))
+	write(strconv.Itoa(len(args)))
+	for _, arg := range args {
+		write(arg)
+	}
+	for _, pattern := range inputs {
+		write(pattern)
+		matches := V(filepath.Glob(filepath.Join(dirPath, pattern)))
+		slices.Sort(matches)
+		for _, match := range matches {
+			info := V(os.Stat(match))
+			if info.IsDir() {
+				continue
+			}
+			write(V(filepath.Rel(dirPath, match)))
+			write(string(V(os.ReadFile(match))))
+		}
+	}
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
+
+// loadGenerateCache returns the map from the directives to the hashes of their inputs. A missing or corrupt file is an empty cache, so that the directives run again and Record rewrites the file.
+func loadGenerateCache(cachePath string) (cache map[string]string, err error) {
+	cache = map[string]string{}
+	content, err := os.ReadFile(cachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return
+	}
+	if json.Unmarshal(content, &cache) != nil {
+		return map[string]string{}, nil
+	}
+	return
+}
+
+// newGenerateDirective returns the directive at the line of the file in the directory, or nil if it has no “//go:generate_input” annotation.
+func newGenerateDirective(cacheDirPath string, dirPath string, fileBase string, line int, cmdPath string, args []string) (directive *GenerateDirective, err error) {
+	defer Catch(&err)
+	filePath := filepath.Join(dirPath, fileBase)
+	inputs, outputs := V2(parseGenerateAnnotations(filePath, line))
+	if len(inputs) == 0 {
+		return
+	}
+	return &GenerateDirective{
+		cachePath: filepath.Join(cacheDirPath, generateCacheBase),
+		// Not the line number but the command line identifies the directive in the file, to survive the edits of the file.
+		key:     strings.Join(append([]string{filePath, filepath.Base(cmdPath)}, args...), "\x00"),
+		hash:    V(hashGenerateInputs(dirPath, cmdPath, args, inputs)),
+		dirPath: dirPath,
+		outputs: outputs,
+	}, nil
+}
+
+// GenerateDirectiveOf returns the “//go:generate” directive which runs the binary with the arguments, if run by “gobin generate” and annotated with “//go:generate_input”. Otherwise, it returns nil.
+func GenerateDirectiveOf(cmdPath string, args []string) (directive *GenerateDirective, err error) {
+	defer Catch(&err)
+	cacheDirPath := os.Getenv(generateCacheEnv)
+	fileBase := os.Getenv("GOFILE")
+	lineStr := os.Getenv("GOLINE")
+	if cacheDirPath == "" || fileBase == "" || lineStr == "" {
+		return
+	}
+	// “go generate” runs the directives in the directory of the package.
+	return newGenerateDirective(cacheDirPath, V(os.Getwd()), fileBase, V(strconv.Atoi(lineStr)), cmdPath, args)
+}
+
+// IsFresh returns true if the inputs, the version and the build flags of the tool, and the arguments are the same as at the last successful run, and every output pattern matches a file.
+func (directive *GenerateDirective) IsFresh() (fresh bool, err error) {
+	defer Catch(&err)
+	cache := V(loadGenerateCache(directive.cachePath))
+	if cache[directive.key] != directive.hash {
+		return
+	}
+	for _, pattern := range directive.outputs {
+		if len(V(filepath.Glob(filepath.Join(directive.dirPath, pattern)))) == 0 {
+			return
+		}
+	}
+	return true, nil
+}
+
+// Record records the hash of the inputs after a successful run.
+func (directive *GenerateDirective) Record() (err error) {
+	defer Catch(&err)
+	cache := V(loadGenerateCache(directive.cachePath))
+	cache[directive.key] = directive.hash
+	V0(os.MkdirAll(filepath.Dir(directive.cachePath), 0755))
+	V0(os.WriteFile(directive.cachePath, V(json.MarshalIndent(cache, "", "  ")), 0644))
+	return
+}
@@ -0,0 +1,116 @@
+package gobin
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	. "github.com/knaka/go-utils"
+	"github.com/knaka/gobin/minlib"
+)
+
+// ForceGenerate makes GenerateCommandEx run all the directives, ignoring the cache of the ones annotated with “//go:generate_input”.
+//
+//goland:noinspection GoUnusedExportedFunction
+func ForceGenerate(f bool) Option {
+	return func(params *installParams) (err error) {
+		params.forceGenerate = f
+		return
+	}
+}
+
+// prepareTools installs the applicable manifest entries in the groups and creates their shims, so that the tools can be run by their base names with the gobin directory on PATH.
+func prepareTools(params *installParams, confDirPath string, gobinPath string) (err error) {
+	defer Catch(&err)
+	global := params.optGlobal != nil && *params.optGlobal
+	// The shims are of no use without the gobin command they link to.
+	V0(minlib.EnsureGobinCmdInstalled(global, params.installOptions()...))
+	manifest := V(parseManifest(confDirPath, withPlatform(params.goos, params.goarch)))
+	var entries []*maniEntry
+	var pkgs []string
+	for _, entry := range manifest.Entries() {
+		if entry.Applicable && entry.inGroups(params.groups) {
+			entries = append(entries, entry)
+			pkgs = append(pkgs, entry.Pkg)
+		}
+	}
+	if len(pkgs) > 0 {
+		// Without targets, install installs nothing or, with the groups, all the packages in the groups.
+		V(install(pkgs, params, confDirPath, gobinPath))
+	}
+	// The shims are created only at the installation, and the binaries may have been installed already.
+	V(createShims(entries, gobinPath, params.shimStrategy))
+	return
+}
+
+// GenerateCommandEx installs all the applicable manifest entries up front and returns the “go generate” command of the managed Go SDK, or of WithGoCmdPath, for the packages with the gobin directory and the managed Go SDK on PATH, so that the “//go:generate” directives can run the tools by their base names without “-command”. The shims skip the directives annotated with “//go:generate_input” whose inputs are not changed since the last successful run (see GenerateDirectiveOf).
+func GenerateCommandEx(args []string, opts ...Option) (cmd *exec.Cmd, err error) {
+	return (&Installer{}).GenerateCommandEx(args, opts...)
+}
+
+// GenerateCommandEx installs all the applicable manifest entries up front and returns the “go generate” command for the packages.
+func (installer *Installer) GenerateCommandEx(args []string, opts ...Option) (cmd *exec.Cmd, err error) {
+	defer Catch(&err)
+	params := V(installer.params(opts))
+	confDirPath, gobinPath := V2(params.dirs())
+	V0(prepareTools(params, confDirPath, gobinPath))
+	if params.forceGenerate {
+		if err_ := os.Remove(filepath.Join(gobinPath, generateCacheBase)); err_ != nil && !errors.Is(err_, os.ErrNotExist) {
+			return nil, err_
+		}
+	}
+	return generateCommand(args, params, gobinPath)
+}
+
+// generateCommand returns the “go generate” command for the packages with the gobin directory and the managed Go SDK on PATH.
+func generateCommand(args []string, params *installParams, gobinPath string) (cmd *exec.Cmd, err error) {
+	defer Catch(&err)
+	// Not “go” in PATH of this process but the one of the managed Go SDK, which is on PATH of the command.
+	goCmdPath := params.goCmdPath
+	if goCmdPath == "" {
+		goCmdPath = filepath.Join(V(params.goroot()), "bin", "go"+Ternary(runtime.GOOS == "windows", ".exe", ""))
+	}
+	cmd = exec.CommandContext(params.ctx, goCmdPath, append([]string{"generate"}, args...)...)
+	cmd.Stdin = params.stdin
+	cmd.Stdout = params.stdout
+	cmd.Stderr = params.stderr
+	if params.Dir != "" {
+		cmd.Dir = params.Dir
+	}
+	cmd.Env = os.Environ()
+	if params.Env != nil {
+		cmd.Env = append(cmd.Env, params.Env...)
+	}
+	cmd.Env = append(cmd.Env,
+		"PATH="+prependedPath(V(pathDirs(params, gobinPath, true))),
+		generateCacheEnv+"="+gobinPath,
+	)
+	return
+}
+
+// GenerateEx runs “go generate” for the packages with GenerateCommandEx.
+//
+//goland:noinspection GoUnusedExportedFunction
+func GenerateEx(args []string, opts ...Option) (errExit *exec.ExitError, err error) {
+	return (&Installer{}).GenerateEx(args, opts...)
+}
+
+// GenerateEx runs “go generate” for the packages with GenerateCommandEx.
+func (installer *Installer) GenerateEx(args []string, opts ...Option) (errExit *exec.ExitError, err error) {
+	defer Catch(&err)
+	params := V(installer.params(opts))
+	cmd := V(installer.GenerateCommandEx(args, opts...))
+	err = contextErr(params.ctx, cmd.Run())
+	if err == nil {
+		return
+	}
+	errExit = ErrorAs[*exec.ExitError](err)
+	return
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func Generate(args ...string) (errExit *exec.ExitError, err error) {
+	return GenerateEx(args)
+}
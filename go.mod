@@ -5,6 +5,7 @@ go 1.23
 toolchain go1.23.1
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/knaka/go-utils v0.1.3
 	github.com/samber/lo v1.46.0
 	github.com/stretchr/testify v1.9.0
@@ -1,15 +1,19 @@
 package gobin
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	stdlog "log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	. "github.com/knaka/go-utils"
 	"github.com/knaka/gobin/log"
@@ -29,6 +33,22 @@ type installParams struct {
 	optVerbose      *bool
 	optSilent       *bool
 	optGlobal       *bool
+	groups          []string
+	goos            string
+	goarch          string
+	shouldInstall   bool
+	shimStrategy    minlib.ShimStrategy
+	seed            string
+	optBootstrap    *bool
+	cleanUpToolsGo  bool
+	forceGenerate   bool
+	confDirPath     string
+	gobinPath       string
+	optLogger       *stdlog.Logger
+	optVLogger      *stdlog.Logger
+	goCmdPath       string
+	goEnv           []string
+	ctx             context.Context
 }
 
 type Option func(params *installParams) error
@@ -41,6 +61,47 @@ func Global(f bool) Option {
 	}
 }
 
+// WithGroups restricts the target packages to the ones which belong to any of the groups. If no package is specified, all the packages in the groups are targeted.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithGroups(groups ...string) Option {
+	return func(params *installParams) (err error) {
+		params.groups = append(params.groups, groups...)
+		return
+	}
+}
+
+// WithPlatform overrides the platform (GOOS and GOARCH) against which the platform conditions of the manifest entries are evaluated. Empty values mean the running platform.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithPlatform(goos string, goarch string) Option {
+	return func(params *installParams) (err error) {
+		params.goos = goos
+		params.goarch = goarch
+		return
+	}
+}
+
+// InstallIfMissing makes WhichEx install the command if its binary does not exist yet.
+//
+//goland:noinspection GoUnusedExportedFunction
+func InstallIfMissing(f bool) Option {
+	return func(params *installParams) (err error) {
+		params.shouldInstall = f
+		return
+	}
+}
+
+// WithShimStrategy sets the way to create the shims of the installed commands. The default tries symlinks, hard links and copies in order.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithShimStrategy(strategy minlib.ShimStrategy) Option {
+	return func(params *installParams) (err error) {
+		params.shimStrategy = strategy
+		return
+	}
+}
+
 //goland:noinspection GoUnusedExportedFunction
 func WithDir(dir string) Option {
 	return func(params *installParams) (err error) {
@@ -69,6 +130,57 @@ func Silent(f bool) Option {
 	}
 }
 
+// WithLogger sets the logger for normal log output instead of the package-level one of the log package.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithLogger(logger *stdlog.Logger) Option {
+	return func(params *installParams) (err error) {
+		params.optLogger = logger
+		return
+	}
+}
+
+// WithVerboseLogger sets the logger for verbose log output instead of the package-level one of the vlog package.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithVerboseLogger(vlogger *stdlog.Logger) Option {
+	return func(params *installParams) (err error) {
+		params.optVLogger = vlogger
+		return
+	}
+}
+
+// WithConfDir sets the configuration directory which has the manifest file and the gobin directory where the binaries are installed, instead of finding them from the working directory. An empty gobin directory means the one in the configuration directory.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithConfDir(confDirPath string, gobinPath string) Option {
+	return func(params *installParams) (err error) {
+		params.confDirPath = confDirPath
+		params.gobinPath = gobinPath
+		return
+	}
+}
+
+// WithGoCmdPath sets the go command which builds and queries the packages instead of the one of the managed Go SDK.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithGoCmdPath(goCmdPath string) Option {
+	return func(params *installParams) (err error) {
+		params.goCmdPath = goCmdPath
+		return
+	}
+}
+
+// WithGoEnv sets the environment variables for the go command, e.g. GOPROXY, GOPRIVATE and GOFLAGS, in addition to the ones of the process.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithGoEnv(env []string) Option {
+	return func(params *installParams) (err error) {
+		params.goEnv = env
+		return
+	}
+}
+
 //goland:noinspection GoUnusedExportedFunction
 func WithEnv(env []string) Option {
 	return func(params *installParams) (err error) {
@@ -121,15 +233,19 @@ func candidateModules(pkg string) (ret []string, err error) {
 	return
 }
 
-func queryVersion(pkg string) (version string, err error) {
-	log.Printf("Querying version for %s\n", pkg)
+func queryVersion(pkg string, params *installParams) (version string, err error) {
+	params.logger().Printf("Querying version for %s\n", pkg)
 	for _, candidate := range V(candidateModules(pkg)) {
-		cmd := exec.Command("go", "list", "-m",
+		cmd := exec.CommandContext(params.ctx, params.goCmd(), "list", "-m",
 			"--json", fmt.Sprintf("%s@%s", candidate, latestVer))
-		cmd.Env = append(os.Environ(), "GO111MODULE=on")
+		cmd.Env = append(append(os.Environ(), params.goEnv...), "GO111MODULE=on")
 		goListOutput := minlib.GoListOutput{}
 		output, err_ := cmd.Output()
 		if err_ != nil {
+			// The failure of the cancelled query does not mean the module does not exist.
+			if params.ctx.Err() != nil {
+				Throw(contextErr(params.ctx, err_))
+			}
 			continue
 		}
 		V0(json.Unmarshal(output, &goListOutput))
@@ -145,66 +261,221 @@ func newInstallParams() *installParams {
 		stdin:         os.Stdin,
 		stdout:        os.Stdout,
 		stderr:        os.Stderr,
+		ctx:           context.Background(),
 	}
 }
 
-func install(targets []string, params *installParams, confDirPath string, gobinPath string) (cmdPath string, err error) {
-	if params.optSilent != nil {
-		log.SetSilent(*params.optSilent)
+// discardLogger is the logger for the output suppressed by Silent or Verbose.
+var discardLogger = stdlog.New(io.Discard, "", 0)
+
+// logger returns the logger for normal log output, which discards the output if Silent is specified.
+func (params *installParams) logger() *stdlog.Logger {
+	if params.optSilent != nil && *params.optSilent {
+		return discardLogger
+	}
+	if params.optLogger != nil {
+		return params.optLogger
+	}
+	return log.Logger()
+}
+
+// vlogger returns the logger for verbose log output. Verbose switches it on or off without touching the package-level logger of the vlog package, which other goroutines may use.
+func (params *installParams) vlogger() *stdlog.Logger {
+	if params.optVLogger != nil {
+		return params.optVLogger
+	}
+	if params.optVerbose == nil {
+		return vlog.Logger()
+	}
+	if !*params.optVerbose {
+		return discardLogger
+	}
+	if params.optLogger != nil {
+		return params.optLogger
+	}
+	return stdlog.New(os.Stderr, vlog.Logger().Prefix(), vlog.Logger().Flags())
+}
+
+// goCmd returns the go command which builds and queries the packages. The managed Go SDK is used only by minlib, and “go” in PATH here as before.
+func (params *installParams) goCmd() string {
+	if params.goCmdPath != "" {
+		return params.goCmdPath
 	}
-	if params.optVerbose != nil {
-		vlog.SetVerbose(*params.optVerbose)
+	return "go"
+}
+
+// goroot returns the GOROOT of the managed Go SDK, downloading it on first use within the context with the output to the writer of WithStderr.
+func (params *installParams) goroot() (string, error) {
+	return minlib.GorootContext(params.ctx, minlib.WithOutput(params.stderr))
+}
+
+// dirs returns the configuration directory and the gobin directory specified by WithConfDir, or the ones found from the working directory or, if Global is specified, the global ones.
+func (params *installParams) dirs() (confDirPath string, gobinPath string, err error) {
+	if params.confDirPath != "" {
+		gobinPath = params.gobinPath
+		if gobinPath == "" {
+			gobinPath = filepath.Join(params.confDirPath, minlib.GobinDirBase)
+		}
+		return params.confDirPath, gobinPath, nil
 	}
+	global := params.optGlobal != nil && *params.optGlobal
+	return minlib.ConfDirPath(minlib.WithGlobal(global))
+}
+
+// installOptions returns the options of minlib which follow the parameters.
+func (params *installParams) installOptions(opts ...minlib.InstallOption) []minlib.InstallOption {
+	return append([]minlib.InstallOption{
+		minlib.WithShimStrategy(params.shimStrategy),
+		minlib.WithGoCmdPath(params.goCmdPath),
+		minlib.WithGoEnv(params.goEnv),
+		minlib.WithVerboseLogger(params.vlogger()),
+		minlib.WithContext(params.ctx),
+		minlib.WithOutput(params.stderr),
+	}, opts...)
+}
+
+func install(targets []string, params *installParams, confDirPath string, gobinPath string) (cmdPath string, err error) {
 	global := params.optGlobal != nil && *params.optGlobal
 	var goModDef *goModDefT
 	if !global {
 		goModDef = V(parseGoMod(confDirPath))
 	}
-	for {
-		if len(targets) == 0 {
-			break
-		}
-		target := targets[0]
-		targets = targets[1:]
+	if len(params.groups) > 0 {
+		targets = V(targetsInGroups(targets, params, confDirPath))
+	}
+	platform := withPlatform(params.goos, params.goarch)
+	graph := &depGraphT{manifest: V(parseManifest(confDirPath, platform)), goModDef: goModDef}
+	// The required commands are installed before the ones which require them.
+	order := V(graph.sort(targets))
+	requiredVersions := V(graph.requiredVersions())
+	// The path of the first target is returned, which is not the first installed.
+	firstKey := ""
+	if len(targets) > 0 {
+		firstKey, _ = graph.node(targets[0])
+	}
+	for _, target := range order {
+		name, _ := splitRequire(target)
 		if !global && goModDef != nil {
-			reqMod := goModDef.requiredModuleByPkg(target)
+			reqMod := goModDef.requiredModuleByPkg(name)
 			if reqMod != nil {
-				cmdPath = Elvis(cmdPath, V(minlib.EnsureInstalled(gobinPath, target, reqMod.Version, "", log.Logger(), vlog.Logger())))
+				installedPath := V(minlib.EnsureInstalled(gobinPath, name, reqMod.Version, "", params.logger(), params.vlogger(),
+					params.installOptions()...,
+				))
+				if name == firstKey {
+					cmdPath = installedPath
+				}
 				continue
 			}
 		}
-		manifest := V(parseManifest(confDirPath))
+		manifest := V(parseManifest(confDirPath, platform))
 		shouldSave := false
-		entry := manifest.lookup(target)
+		// The version of a required command, e.g. “stringer@v0.23.0”, is not a part of the name to find.
+		entry, errFind := manifest.find(name)
 		if entry != nil {
-			targets = append(targets, entry.Requires...)
+			if !entry.Applicable {
+				params.logger().Printf("Skipping %s which is not applicable to the platform\n", entry.Pkg)
+				continue
+			}
 			if entry.LockedVersion == latestVer {
-				entry.LockedVersion = V(queryVersion(entry.Pkg))
+				// The version required by another entry is locked instead of the latest one.
+				if requiredVersion, ok := requiredVersions[entry.Pkg]; ok {
+					entry.LockedVersion = requiredVersion
+				} else {
+					entry.LockedVersion = V(queryVersion(entry.Pkg, params))
+				}
 				shouldSave = true
 			}
-			cmdPath = Elvis(cmdPath, V(minlib.EnsureInstalled(gobinPath, entry.Pkg, entry.LockedVersion, entry.Tags, log.Logger(), vlog.Logger())))
+			installedPath := V(minlib.EnsureInstalled(gobinPath, entry.Pkg, entry.LockedVersion, entry.Tags, params.logger(), params.vlogger(),
+				params.installOptions(
+					minlib.WithLdflags(entry.Ldflags),
+					minlib.WithBuildEnv(entry.Env),
+				)...,
+			))
+			if entry.Pkg == firstKey {
+				cmdPath = installedPath
+			}
 			if shouldSave {
 				V0(manifest.saveLockfile())
 			}
 			continue
 		}
-		err = errors.New(fmt.Sprintf("command “%s” is not defined", target))
+		// A required command which gobin does not manage, e.g. “protoc”, should be installed by other means.
+		if !slices.Contains(targets, target) && errors.Is(errFind, ErrToolNotDefined) {
+			if cmdPath_, err_ := exec.LookPath(name); err_ == nil {
+				params.vlogger().Printf("Using %s in PATH\n", cmdPath_)
+				continue
+			}
+			err = newKindError(ErrToolNotDefined, "required command “%s” is neither defined in the manifest nor found in PATH", name)
+			return
+		}
+		err = errFind
 		return
 	}
 	return
 }
 
-func InstallEx(patterns []string, opts ...Option) (cmdPath string, err error) {
+// resolveCmdPath returns the path of the binary which install installs for the target, without installing it. The version is resolved in the same order as install, from go.mod, then from the manifest and the lock file.
+func resolveCmdPath(target string, params *installParams, confDirPath string, gobinPath string) (cmdPath string, err error) {
 	defer Catch(&err)
-	params := newInstallParams()
-	for _, opt := range opts {
-		V0(opt(params))
+	global := params.optGlobal != nil && *params.optGlobal
+	if !global {
+		if goModDef := V(parseGoMod(confDirPath)); goModDef != nil {
+			if reqMod := goModDef.requiredModuleByPkg(target); reqMod != nil {
+				return minlib.InstalledCmdPath(gobinPath, target, reqMod.Version, ""), nil
+			}
+		}
 	}
-	var goModOptions []minlib.ConfDirPathOption
-	if params.optGlobal != nil {
-		goModOptions = append(goModOptions, minlib.WithGlobal(*params.optGlobal))
+	manifest := V(parseManifest(confDirPath, withPlatform(params.goos, params.goarch)))
+	entry := V(manifest.find(target))
+	if !entry.Applicable {
+		err = errors.New(fmt.Sprintf("command “%s” is not available on this platform", target))
+		return
 	}
-	confDirPath, gobinPath := V2(minlib.ConfDirPath(goModOptions...))
+	if entry.LockedVersion == latestVer {
+		err = errors.New(fmt.Sprintf("the version of command “%s” is not locked yet", target))
+		return
+	}
+	cmdPath = minlib.InstalledCmdPath(gobinPath, entry.Pkg, entry.LockedVersion, entry.Tags,
+		minlib.WithLdflags(entry.Ldflags),
+		minlib.WithBuildEnv(entry.Env),
+	)
+	return
+}
+
+// targetsInGroups returns the targets which belong to any of the groups. If no target is specified, it returns all the packages in the groups.
+func targetsInGroups(targets []string, params *installParams, confDirPath string) (ret []string, err error) {
+	defer Catch(&err)
+	groups := params.groups
+	manifest := V(parseManifest(confDirPath))
+	if len(targets) == 0 {
+		for _, entry := range manifest.Entries() {
+			if entry.inGroups(groups) {
+				ret = append(ret, entry.Pkg)
+			}
+		}
+		return
+	}
+	for _, target := range targets {
+		entry := manifest.lookup(target)
+		if entry == nil || !entry.inGroups(groups) {
+			params.vlogger().Printf("Skipping %s which is not in the groups %s\n", target, strings.Join(groups, ","))
+			continue
+		}
+		ret = append(ret, target)
+	}
+	return
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func InstallEx(patterns []string, opts ...Option) (cmdPath string, err error) {
+	return (&Installer{}).InstallEx(patterns, opts...)
+}
+
+// InstallEx installs the packages and returns the path of the binary of the first one.
+func (installer *Installer) InstallEx(patterns []string, opts ...Option) (cmdPath string, err error) {
+	defer Catch(&err)
+	params := V(installer.params(opts))
+	confDirPath, gobinPath := V2(params.dirs())
 	return install(patterns, params, confDirPath, gobinPath)
 }
 
@@ -213,23 +484,26 @@ func Install(patterns ...string) (cmdPath string, err error) {
 	return InstallEx(patterns)
 }
 
+//goland:noinspection GoUnusedExportedFunction
 func CommandEx(args []string, opts ...Option) (cmd *exec.Cmd, err error) {
+	return (&Installer{}).CommandEx(args, opts...)
+}
+
+// CommandEx installs the command if needed and returns the command to run it with the arguments.
+func (installer *Installer) CommandEx(args []string, opts ...Option) (cmd *exec.Cmd, err error) {
 	defer Catch(&err)
 	if len(args) == 0 {
 		err = errors.New("no command specified")
 		return
 	}
-	params := newInstallParams()
-	for _, opt := range opts {
-		V0(opt(params))
-	}
-	var goModOptions []minlib.ConfDirPathOption
-	if params.optGlobal != nil {
-		goModOptions = append(goModOptions, minlib.WithGlobal(*params.optGlobal))
-	}
-	confDirPath, gobinPath := V2(minlib.ConfDirPath(goModOptions...))
+	params := V(installer.params(opts))
+	confDirPath, gobinPath := V2(params.dirs())
 	cmdPath := V(install([]string{args[0]}, params, confDirPath, gobinPath))
-	cmd = exec.Command(cmdPath, args[1:]...)
+	if cmdPath == "" {
+		err = errors.New(fmt.Sprintf("command “%s” is not available on this platform", args[0]))
+		return
+	}
+	cmd = exec.CommandContext(params.ctx, cmdPath, args[1:]...)
 	cmd.Stdin = params.stdin
 	cmd.Stdout = params.stdout
 	cmd.Stderr = params.stderr
@@ -240,11 +514,7 @@ func CommandEx(args []string, opts ...Option) (cmd *exec.Cmd, err error) {
 	if params.Env != nil {
 		cmd.Env = append(cmd.Env, params.Env...)
 	}
-	if params.WithGobinPath {
-		cmd.Env = append(cmd.Env, "PATH="+gobinPath+string(filepath.ListSeparator)+os.Getenv("PATH"))
-	}
-	binDirPath := filepath.Join(V(minlib.Goroot()), "bin")
-	cmd.Env = append(cmd.Env, "PATH="+binDirPath+string(filepath.ListSeparator)+os.Getenv("PATH"))
+	cmd.Env = append(cmd.Env, "PATH="+prependedPath(V(pathDirs(params, gobinPath, params.WithGobinPath))))
 	return
 }
 
@@ -255,10 +525,16 @@ func Command(args ...string) (cmd *exec.Cmd, err error) {
 
 //goland:noinspection GoUnusedExportedFunction
 func RunEx(args []string, opts ...Option) (errExit *exec.ExitError, err error) {
+	return (&Installer{}).RunEx(args, opts...)
+}
+
+// RunEx runs the command of CommandEx and returns the exit error if it fails.
+func (installer *Installer) RunEx(args []string, opts ...Option) (errExit *exec.ExitError, err error) {
 	defer Catch(&err)
-	cmd := V(CommandEx(args, opts...))
-	vlog.Printf("Running %s\n", cmd.Path)
-	err = cmd.Run()
+	params := V(installer.params(opts))
+	cmd := V(installer.CommandEx(args, opts...))
+	params.vlogger().Printf("Running %s\n", cmd.Path)
+	err = contextErr(params.ctx, cmd.Run())
 	if err == nil {
 		return
 	}
@@ -266,43 +542,66 @@ func RunEx(args []string, opts ...Option) (errExit *exec.ExitError, err error) {
 	return
 }
 
+// WhichEx returns the absolute path of the binary which CommandEx would run for the name, without running it. It fails if the binary does not exist yet unless InstallIfMissing is specified.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WhichEx(name string, opts ...Option) (cmdPath string, err error) {
+	return (&Installer{}).WhichEx(name, opts...)
+}
+
+// WhichEx returns the absolute path of the binary which CommandEx would run for the name, without running it.
+func (installer *Installer) WhichEx(name string, opts ...Option) (cmdPath string, err error) {
+	defer Catch(&err)
+	params := V(installer.params(opts))
+	confDirPath, gobinPath := V2(params.dirs())
+	if params.shouldInstall {
+		cmdPath = V(install([]string{name}, params, confDirPath, gobinPath))
+		if cmdPath == "" {
+			err = errors.New(fmt.Sprintf("command “%s” is not available on this platform", name))
+		}
+		return
+	}
+	cmdPath = V(resolveCmdPath(name, params, confDirPath, gobinPath))
+	if _, err_ := os.Stat(cmdPath); err_ != nil {
+		err = errors.New(fmt.Sprintf("command “%s” is not installed yet at %s", name, cmdPath))
+		return
+	}
+	return
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func Which(name string) (cmdPath string, err error) {
+	return WhichEx(name)
+}
+
 //goland:noinspection GoUnusedExportedFunction
 func Run(args ...string) (errExit *exec.ExitError, err error) {
 	return RunEx(args)
 }
 
+//goland:noinspection GoUnusedExportedFunction
 func UpdateEx(patterns []string, opts ...Option) (err error) {
-	params := newInstallParams()
-	for _, opt := range opts {
-		V0(opt(params))
-	}
-	if params.optSilent != nil {
-		log.SetSilent(*params.optSilent)
-	}
-	if params.optVerbose != nil {
-		vlog.SetVerbose(*params.optVerbose)
-	}
-	var goModOptions []minlib.ConfDirPathOption
-	if params.optGlobal != nil {
-		goModOptions = append(goModOptions, minlib.WithGlobal(*params.optGlobal))
-	}
-	confDirPath, _ := V2(minlib.ConfDirPath(goModOptions...))
+	return (&Installer{}).UpdateEx(patterns, opts...)
+}
+
+// UpdateEx locks the packages of the “latest” version to the latest version now.
+func (installer *Installer) UpdateEx(patterns []string, opts ...Option) (err error) {
+	defer Catch(&err)
+	params := V(installer.params(opts))
+	confDirPath, _ := V2(params.dirs())
 	manifest := V(parseManifest(confDirPath))
 	var latestEntries []*maniEntry
 	if len(patterns) == 0 {
 		latestEntries = lo.Filter(manifest.Entries(), func(entry *maniEntry, _ int) (f bool) {
-			if entry.Version == latestVer {
+			if entry.Version == latestVer && entry.inGroups(params.groups) {
 				f = true
 			}
 			return
 		})
 	} else {
 		latestEntries = lo.FilterMap(patterns, func(pattern string, _ int) (entry *maniEntry, f bool) {
-			entry = manifest.lookup(pattern)
-			if entry == nil {
-				Throw(errors.New(fmt.Sprintf("command “%s” is not defined", pattern)))
-			}
-			if entry.Version == latestVer {
+			entry = V(manifest.find(pattern))
+			if entry.Version == latestVer && entry.inGroups(params.groups) {
 				f = true
 			}
 			return
@@ -310,11 +609,11 @@ func UpdateEx(patterns []string, opts ...Option) (err error) {
 	}
 	for _, entry := range latestEntries {
 		oldVersion := entry.LockedVersion
-		entry.LockedVersion = V(queryVersion(entry.Pkg))
+		entry.LockedVersion = V(queryVersion(entry.Pkg, params))
 		if oldVersion != entry.LockedVersion {
-			log.Printf("Updated %s from %s to %s\n", entry.Pkg, oldVersion, entry.LockedVersion)
+			params.logger().Printf("Updated %s from %s to %s\n", entry.Pkg, oldVersion, entry.LockedVersion)
 		} else {
-			log.Printf("No update for %s@%s -> %s\n", entry.Pkg, entry.Version, entry.LockedVersion)
+			params.logger().Printf("No update for %s@%s -> %s\n", entry.Pkg, entry.Version, entry.LockedVersion)
 		}
 	}
 	V0(manifest.saveLockfile())
@@ -325,23 +624,139 @@ type ListEntry struct {
 	Pkg           string
 	Version       string
 	LockedVersion string
+	// Description describes the command.
+	Description string `json:",omitempty"`
+	// Tags are the comma-separated build tags.
+	Tags string `json:",omitempty"`
+	// Requires are the commands required to run the command.
+	Requires []string `json:",omitempty"`
+	// BinPath is the path of the binary of the locked version. It is empty if the version is not locked yet.
+	BinPath string `json:",omitempty"`
+	// Cached is true if the binary exists.
+	Cached bool
+	// Size and ModTime are the size and the modification time of the binary if cached.
+	Size    int64      `json:",omitempty"`
+	ModTime *time.Time `json:",omitempty"`
+	// Origin is where the version comes from, OriginManifest or OriginGoMod.
+	Origin string
+	// Applicable is true if the entry is applicable to the target platform.
+	Applicable bool
+	// Groups are the names of the groups which the entry belongs to.
+	Groups []string `json:",omitempty"`
+	// Source is the path of the file which defines the entry.
+	Source string
 }
 
-func List(global bool) (ret []*ListEntry, err error) {
-	confDirPath, _ := V2(minlib.ConfDirPath(minlib.WithGlobal(global)))
-	manifest, err := parseManifest(confDirPath)
-	if err != nil {
-		return
+// Origins of the versions of the list entries.
+const (
+	// OriginManifest means the version comes from the manifest file or its lock file.
+	OriginManifest = "Gobinfile"
+	// OriginGoMod means the version comes from the module required in go.mod, which takes precedence over the manifest on install.
+	OriginGoMod = "go.mod"
+)
+
+// newListEntry returns the list entry of the manifest entry. The version is resolved in the same way as install, so the module required in go.mod takes precedence.
+func newListEntry(entry *maniEntry, gobinPath string, goModDef *goModDefT) *ListEntry {
+	listEntry := &ListEntry{
+		Pkg:           entry.Pkg,
+		Version:       entry.Version,
+		LockedVersion: entry.LockedVersion,
+		Description:   entry.Description,
+		Tags:          entry.Tags,
+		Requires:      entry.Requires,
+		Applicable:    entry.Applicable,
+		Groups:        entry.Groups,
+		Source:        entry.Source,
+		Origin:        OriginManifest,
+	}
+	if goModDef != nil {
+		if reqMod := goModDef.requiredModuleByPkg(entry.Pkg); reqMod != nil {
+			listEntry.LockedVersion = reqMod.Version
+			listEntry.Origin = OriginGoMod
+			listEntry.BinPath = minlib.InstalledCmdPath(gobinPath, entry.Pkg, reqMod.Version, "")
+		}
 	}
-	for _, entry := range manifest.Entries() {
-		ret = append(ret, &ListEntry{
-			Pkg:           entry.Pkg,
-			Version:       entry.Version,
-			LockedVersion: entry.LockedVersion,
-		})
+	if listEntry.Origin == OriginManifest && entry.LockedVersion != latestVer {
+		listEntry.BinPath = minlib.InstalledCmdPath(gobinPath, entry.Pkg, entry.LockedVersion, entry.Tags,
+			minlib.WithLdflags(entry.Ldflags),
+			minlib.WithBuildEnv(entry.Env),
+		)
+	}
+	if listEntry.BinPath != "" {
+		if stat, err := os.Stat(listEntry.BinPath); err == nil && !stat.IsDir() {
+			listEntry.Cached = true
+			listEntry.Size = stat.Size()
+			listEntry.ModTime = P(stat.ModTime())
+		}
+	}
+	return listEntry
+}
+
+// ListEx returns the packages listed in the manifest, and the main packages in go.mod which are not in the manifest (see OriginGoMod). The packages can be restricted by WithGroups.
+func ListEx(opts ...Option) (ret []*ListEntry, err error) {
+	return (&Installer{}).ListEx(opts...)
+}
+
+// ListEx returns the packages listed in the manifest, and the main packages in go.mod which are not in the manifest.
+func (installer *Installer) ListEx(opts ...Option) (ret []*ListEntry, err error) {
+	defer Catch(&err)
+	params := V(installer.params(opts))
+	global := params.optGlobal != nil && *params.optGlobal
+	confDirPath, gobinPath := V2(params.dirs())
+	manifest := V(parseManifest(confDirPath, withPlatform(params.goos, params.goarch)))
+	var goModDef *goModDefT
+	if !global {
+		goModDef = V(parseGoMod(confDirPath))
+	}
+	entries := slices.Clone(manifest.Entries())
+	if goModDef != nil {
+		// The tools only in go.mod, which install takes from there, belong to no group.
+		for _, pkg := range V(goModDef.toolPkgsOf(confDirPath)) {
+			if !slices.ContainsFunc(entries, func(entry *maniEntry) bool { return entry.Pkg == pkg }) {
+				version := goModDef.requiredModuleByPkg(pkg).Version
+				entries = append(entries, &maniEntry{
+					Pkg:           pkg,
+					Version:       version,
+					LockedVersion: version,
+					Applicable:    true,
+					Source:        filepath.Join(confDirPath, goModBase),
+				})
+			}
+		}
+	}
+	for _, entry := range entries {
+		if !entry.inGroups(params.groups) {
+			continue
+		}
+		ret = append(ret, newListEntry(entry, gobinPath, goModDef))
 	}
 	sort.Slice(ret, func(i, j int) bool {
 		return ret[i].Pkg < ret[j].Pkg
 	})
 	return
 }
+
+func List(global bool) (ret []*ListEntry, err error) {
+	return ListEx(Global(global))
+}
+
+// Lookup returns the entry of the manifest which matches the pattern, which is a package path or the base name of a package.
+func Lookup(pattern string, opts ...Option) (entry *ListEntry, err error) {
+	return (&Installer{}).Lookup(pattern, opts...)
+}
+
+// Lookup returns the entry of the manifest which matches the pattern.
+func (installer *Installer) Lookup(pattern string, opts ...Option) (entry *ListEntry, err error) {
+	defer Catch(&err)
+	params := V(installer.params(opts))
+	global := params.optGlobal != nil && *params.optGlobal
+	confDirPath, gobinPath := V2(params.dirs())
+	manifest := V(parseManifest(confDirPath, withPlatform(params.goos, params.goarch)))
+	maniEntry := V(manifest.find(pattern))
+	var goModDef *goModDefT
+	if !global {
+		goModDef = V(parseGoMod(confDirPath))
+	}
+	entry = newListEntry(maniEntry, gobinPath, goModDef)
+	return
+}
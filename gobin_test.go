@@ -1,14 +1,24 @@
 package gobin
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	fsutils "github.com/knaka/go-utils/fs"
 	"github.com/knaka/gobin/minlib"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
+	stdlog "log"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/knaka/go-utils"
 )
@@ -132,7 +142,7 @@ func Test_queryVersion(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotVersion, err := queryVersion(tt.args.pkg)
+			gotVersion, err := queryVersion(tt.args.pkg, newInstallParams())
 			if !tt.wantErr(t, err, fmt.Sprintf("queryVersion(%v)", tt.args.pkg)) {
 				return
 			}
@@ -140,3 +150,983 @@ func Test_queryVersion(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseManifestLayered(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+
+	childDirPath := filepath.Join(tempDir, "child")
+	V0(os.MkdirAll(childDirPath, 0755))
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(`
+golang.org/x/tools/cmd/stringer@v0.22.0
+github.com/hairyhenderson/gomplate/v4/cmd/gomplate@latest
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, maniLockBase), []byte(`
+github.com/hairyhenderson/gomplate/v4/cmd/gomplate@v4.1.0
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, "Gobinfile.base"), []byte(`
+github.com/oNaiPs/go-generate-fast@v0.3.0
+`), 0644))
+	V0(os.WriteFile(filepath.Join(childDirPath, maniBase), []byte(`
+inherit
+include ../Gobinfile.base
+golang.org/x/tools/cmd/stringer@v0.23.0
+`), 0644))
+
+	manifest := V(parseManifest(childDirPath))
+	assert.Len(t, manifest.Entries(), 3)
+
+	entry := manifest.lookup("stringer")
+	assert.Equal(t, "v0.23.0", entry.Version)
+	assert.Equal(t, filepath.Join(childDirPath, maniBase), entry.Source)
+
+	entry = manifest.lookup("gomplate")
+	assert.Equal(t, "v4.1.0", entry.LockedVersion)
+	assert.Equal(t, filepath.Join(tempDir, maniBase), entry.Source)
+
+	entry = manifest.lookup("go-generate-fast")
+	assert.Equal(t, "v0.3.0", entry.Version)
+	assert.Equal(t, filepath.Join(tempDir, "Gobinfile.base"), entry.Source)
+
+	V0(os.WriteFile(filepath.Join(tempDir, "Gobinfile.base"), []byte(`
+include child/Gobinfile
+`), 0644))
+	_, err := parseManifest(childDirPath)
+	assert.Error(t, err)
+}
+
+func Test_parseManifestGroups(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(`
+golang.org/x/tools/cmd/stringer@v0.23.0 groups=ci,dev
+[dev]
+golang.org/x/tools/gopls@v0.16.1
+github.com/go-delve/delve/cmd/dlv@v1.23.0 groups=debug
+`), 0644))
+
+	manifest := V(parseManifest(tempDir))
+	assert.Equal(t, []string{"ci", "dev"}, manifest.lookup("stringer").Groups)
+	assert.Equal(t, []string{"dev"}, manifest.lookup("gopls").Groups)
+	assert.Equal(t, []string{"debug", "dev"}, manifest.lookup("dlv").Groups)
+
+	targets := V(targetsInGroups(nil, &installParams{groups: []string{"ci"}}, tempDir))
+	assert.Equal(t, []string{"golang.org/x/tools/cmd/stringer"}, targets)
+	targets = V(targetsInGroups([]string{"stringer", "gopls"}, &installParams{groups: []string{"ci"}}, tempDir))
+	assert.Equal(t, []string{"stringer"}, targets)
+	targets = V(targetsInGroups(nil, &installParams{groups: []string{"dev"}}, tempDir))
+	assert.Len(t, targets, 3)
+}
+
+func Test_parseManifestPlatform(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(`
+golang.org/x/tools/cmd/stringer@v0.23.0
+github.com/cilium/ebpf/cmd/bpf2go@v0.16.0 os=linux arch=amd64,arm64
+github.com/go-delve/delve/cmd/dlv@v1.23.0 build=unix&&!386
+`), 0644))
+
+	manifest := V(parseManifest(tempDir, withPlatform("linux", "amd64")))
+	assert.True(t, manifest.lookup("stringer").Applicable)
+	assert.True(t, manifest.lookup("bpf2go").Applicable)
+	assert.True(t, manifest.lookup("dlv").Applicable)
+
+	manifest = V(parseManifest(tempDir, withPlatform("darwin", "arm64")))
+	assert.True(t, manifest.lookup("stringer").Applicable)
+	assert.False(t, manifest.lookup("bpf2go").Applicable)
+	assert.True(t, manifest.lookup("dlv").Applicable)
+
+	manifest = V(parseManifest(tempDir, withPlatform("windows", "amd64")))
+	assert.False(t, manifest.lookup("bpf2go").Applicable)
+	assert.False(t, manifest.lookup("dlv").Applicable)
+
+	// “android” implies “linux”, and “ios” implies “darwin”.
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(`
+github.com/cilium/ebpf/cmd/bpf2go@v0.16.0 os=linux
+golang.org/x/tools/cmd/stringer@v0.23.0 build=darwin
+golang.org/x/tools/cmd/goyacc@v0.23.0 build=linux&&!android
+`), 0644))
+	manifest = V(parseManifest(tempDir, withPlatform("android", "arm64")))
+	assert.True(t, manifest.lookup("bpf2go").Applicable)
+	assert.False(t, manifest.lookup("stringer").Applicable)
+	assert.False(t, manifest.lookup("goyacc").Applicable)
+	manifest = V(parseManifest(tempDir, withPlatform("ios", "arm64")))
+	assert.False(t, manifest.lookup("bpf2go").Applicable)
+	assert.True(t, manifest.lookup("stringer").Applicable)
+
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(`
+github.com/go-delve/delve/cmd/dlv@v1.23.0 build=linux&&
+`), 0644))
+	_, err := parseManifest(tempDir)
+	assert.Error(t, err)
+}
+
+func Test_parseManifestTOML(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+
+	V0(os.WriteFile(filepath.Join(tempDir, maniTOMLBase), []byte(`
+[tools.sqlc]
+pkg = "github.com/sqlc-dev/sqlc/cmd/sqlc"
+description = "Generates type-safe code from SQL"
+tags = ["foo", "bar"]
+env = ["CGO_ENABLED=0"]
+ldflags = "-s -w"
+requires = ["stringer"]
+groups = ["ci"]
+
+[tools.stringer]
+pkg = "golang.org/x/tools/cmd/stringer"
+version = "v0.23.0"
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, maniLockBase), []byte(`
+github.com/sqlc-dev/sqlc/cmd/sqlc@v1.27.0
+`), 0644))
+
+	manifest := V(parseManifest(tempDir))
+	assert.Len(t, manifest.Entries(), 2)
+	entry := manifest.lookup("sqlc")
+	assert.Equal(t, "latest", entry.Version)
+	assert.Equal(t, "v1.27.0", entry.LockedVersion)
+	assert.Equal(t, "foo,bar", entry.Tags)
+	assert.Equal(t, []string{"CGO_ENABLED=0"}, entry.Env)
+	assert.Equal(t, "-s -w", entry.Ldflags)
+	assert.Equal(t, "Generates type-safe code from SQL", entry.Description)
+	assert.Equal(t, []string{"stringer"}, entry.Requires)
+	assert.Equal(t, []string{"ci"}, entry.Groups)
+	assert.Equal(t, "v0.23.0", manifest.lookup("stringer").Version)
+
+	// Round trip through the line format keeps the attributes the line format can represent.
+	maniFile := V(loadManifestFile(filepath.Join(tempDir, maniTOMLBase)))
+	linePath := filepath.Join(tempDir, "line", maniBase)
+	V0(os.MkdirAll(filepath.Dir(linePath), 0755))
+	V0(saveLineManifestFile(maniFile, linePath, newInstallParams()))
+	maniFile = V(loadManifestFile(linePath))
+	assert.Equal(t, "foo,bar", maniFile.entries[0].Tags)
+	assert.Equal(t, []string{"ci"}, maniFile.entries[0].Groups)
+	assert.Equal(t, "", maniFile.entries[0].Ldflags)
+	tomlPath := filepath.Join(tempDir, "line", maniTOMLBase)
+	V0(saveTOMLManifestFile(maniFile, tomlPath))
+	maniFile = V(loadManifestFile(tomlPath))
+	assert.Equal(t, "github.com/sqlc-dev/sqlc/cmd/sqlc", maniFile.entries[0].Pkg)
+	assert.Equal(t, "golang.org/x/tools/cmd/stringer", maniFile.entries[1].Pkg)
+
+	_, err := manifestFilePath(filepath.Join(tempDir, "line"))
+	assert.Error(t, err)
+}
+
+func TestMigrateManifest(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	V0(os.WriteFile(filepath.Join(tempDir, maniTOMLBase), []byte(`
+[tools.bpf2go]
+pkg = "github.com/cilium/ebpf/cmd/bpf2go"
+build = "linux && amd64"
+tags = ["a b", "c"]
+groups = ["dev tools"]
+ldflags = "-s -w"
+`), 0644))
+
+	// The values with spaces are quoted so that the line format reads them back.
+	var logs bytes.Buffer
+	V0(MigrateManifest(ManifestFormatLine, WithConfDir(tempDir, ""), WithLogger(stdlog.New(&logs, "", 0))))
+	// The dropped attributes are logged to the logger of the options.
+	assert.Contains(t, logs.String(), "Dropping ldflags of github.com/cilium/ebpf/cmd/bpf2go")
+	content := string(V(os.ReadFile(filepath.Join(tempDir, maniBase))))
+	assert.Equal(t, `github.com/cilium/ebpf/cmd/bpf2go@latest tags="a b,c" groups="dev tools" build="linux && amd64"`+"\n", content)
+	_, err := os.Stat(filepath.Join(tempDir, maniTOMLBase))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	entry := V(parseManifest(tempDir)).lookup("bpf2go")
+	assert.Equal(t, "linux && amd64", entry.BuildConstraint)
+	assert.Equal(t, "a b,c", entry.Tags)
+	assert.Equal(t, []string{"dev tools"}, entry.Groups)
+
+	V0(MigrateManifest(ManifestFormatTOML, WithConfDir(tempDir, ""), Silent(true)))
+	entry = V(parseManifest(tempDir)).lookup("bpf2go")
+	assert.Equal(t, "linux && amd64", entry.BuildConstraint)
+	assert.Equal(t, "a b,c", entry.Tags)
+}
+
+func TestCheckManifest(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte("golang.org/x/tools/cmd/stringer@latest\n"), 0644))
+	lockPath := filepath.Join(tempDir, maniLockBase)
+	V0(os.WriteFile(lockPath, []byte(`
+golang.org/x/tools/cmd/stringer@v0.23.0
+Golang.org/../stringer@v0.23.0
+golang.org/x/tools/cmd/goyacc@0.23.0
+`), 0644))
+	// The problems of the lock file are reported with their line numbers.
+	err := CheckManifest(WithConfDir(tempDir, ""))
+	assert.ErrorContains(t, err, lockPath+":3: malformed package path “Golang.org/../stringer”")
+	assert.ErrorContains(t, err, lockPath+":4: invalid locked version “0.23.0” of golang.org/x/tools/cmd/goyacc")
+}
+
+func Test_loadLineManifestFileErrors(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+
+	filePath := filepath.Join(tempDir, maniBase)
+	V0(os.WriteFile(filePath, []byte(`# Tools
+golang.org/x/tools/cmd/stringer@v0.23.0 tag=foo
+github.com/hairyhenderson/gomplate/v4/cmd/gomplate@latest requires
+Golang.org/../stringer@v0.23.0
+golang.org/x/tools/cmd/goyacc@0.23.0
+`), 0644))
+	_, err := loadLineManifestFile(filePath)
+	assert.Error(t, err)
+	msg := err.Error()
+	assert.Contains(t, msg, filePath+":2: unknown option “tag”")
+	assert.Contains(t, msg, filePath+":3: malformed option “requires”")
+	assert.Contains(t, msg, filePath+":4: malformed package path")
+	assert.Contains(t, msg, filePath+":5: invalid version “0.23.0”")
+}
+
+func Test_splitManifestLine(t *testing.T) {
+	fields := V(splitManifestLine(`golang.org/x/tools/cmd/stringer@latest desc="Generates # String() methods" tags=foo # comment`))
+	assert.Equal(t, []string{
+		"golang.org/x/tools/cmd/stringer@latest",
+		`desc="Generates # String() methods"`,
+		"tags=foo",
+	}, fields)
+	fields = V(splitManifestLine(`  # comment only`))
+	assert.Empty(t, fields)
+	_, err := splitManifestLine(`golang.org/x/tools/cmd/stringer@latest desc="unterminated`)
+	assert.Error(t, err)
+}
+
+func Test_parseManifestDescriptions(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(`
+#: Generates String() methods
+#: for constants.
+golang.org/x/tools/cmd/stringer@v0.23.0
+github.com/sqlc-dev/sqlc/cmd/sqlc@v1.27.0 desc="Generates \"type-safe\" code"
+#: Not for the next entry.
+
+github.com/hairyhenderson/gomplate/v4/cmd/gomplate@v4.1.0
+`), 0644))
+	manifest := V(parseManifest(tempDir))
+	assert.Equal(t, "Generates String() methods for constants.", manifest.lookup("stringer").Description)
+	assert.Equal(t, `Generates "type-safe" code`, manifest.lookup("sqlc").Description)
+	assert.Equal(t, "", manifest.lookup("gomplate").Description)
+
+	line, lost := manifest.lookup("sqlc").line()
+	assert.Equal(t, `github.com/sqlc-dev/sqlc/cmd/sqlc@v1.27.0 desc="Generates \"type-safe\" code"`, line)
+	assert.Empty(t, lost)
+}
+
+func Test_newListEntry(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	gobinPath := filepath.Join(tempDir, minlib.GobinDirBase)
+	V0(os.MkdirAll(gobinPath, 0755))
+
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(`
+golang.org/x/tools/cmd/stringer@v0.23.0
+github.com/hairyhenderson/gomplate/v4/cmd/gomplate@latest tags=foo
+github.com/sqlc-dev/sqlc/cmd/sqlc@latest
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, maniLockBase), []byte(`
+github.com/hairyhenderson/gomplate/v4/cmd/gomplate@v4.1.0
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, goModBase), []byte(`module example.com/foo
+
+go 1.23
+
+require golang.org/x/tools v0.24.0
+`), 0644))
+	manifest := V(parseManifest(tempDir))
+	goModDef := V(parseGoMod(tempDir))
+
+	entry := newListEntry(manifest.lookup("stringer"), gobinPath, goModDef)
+	assert.Equal(t, OriginGoMod, entry.Origin)
+	assert.Equal(t, "v0.24.0", entry.LockedVersion)
+	assert.Equal(t, minlib.InstalledCmdPath(gobinPath, entry.Pkg, "v0.24.0", ""), entry.BinPath)
+	assert.False(t, entry.Cached)
+
+	entry = newListEntry(manifest.lookup("gomplate"), gobinPath, goModDef)
+	assert.Equal(t, OriginManifest, entry.Origin)
+	assert.Equal(t, minlib.InstalledCmdPath(gobinPath, entry.Pkg, "v4.1.0", "foo"), entry.BinPath)
+	V0(os.WriteFile(entry.BinPath, []byte("binary"), 0755))
+	entry = newListEntry(manifest.lookup("gomplate"), gobinPath, goModDef)
+	assert.True(t, entry.Cached)
+	assert.Equal(t, int64(len("binary")), entry.Size)
+	assert.False(t, entry.ModTime.IsZero())
+
+	entry = newListEntry(manifest.lookup("sqlc"), gobinPath, goModDef)
+	assert.Equal(t, "", entry.BinPath)
+	assert.False(t, entry.Cached)
+	// The modification time of the binary not built yet is omitted.
+	assert.Nil(t, entry.ModTime)
+	assert.NotContains(t, string(V(json.Marshal(entry))), "ModTime")
+
+	// The main packages only in go.mod are listed with no group.
+	V0(os.WriteFile(filepath.Join(tempDir, goModBase), []byte(`module example.com/foo
+
+go 1.23
+
+require (
+	golang.org/x/tools v0.24.0
+	github.com/stretchr/testify v1.9.0
+)
+
+tool golang.org/x/tools/cmd/goyacc
+`), 0644))
+	entries := V(ListEx(WithConfDir(tempDir, gobinPath)))
+	assert.Equal(t, []string{
+		"github.com/hairyhenderson/gomplate/v4/cmd/gomplate",
+		"github.com/sqlc-dev/sqlc/cmd/sqlc",
+		"golang.org/x/tools/cmd/goyacc",
+		"golang.org/x/tools/cmd/stringer",
+	}, lo.Map(entries, func(entry *ListEntry, _ int) string { return entry.Pkg }))
+	assert.Equal(t, OriginGoMod, entries[2].Origin)
+	assert.Equal(t, "v0.24.0", entries[2].LockedVersion)
+	assert.Equal(t, filepath.Join(tempDir, goModBase), entries[2].Source)
+	assert.Equal(t, minlib.InstalledCmdPath(gobinPath, "golang.org/x/tools/cmd/goyacc", "v0.24.0", ""), entries[2].BinPath)
+	assert.Len(t, V(ListEx(WithConfDir(tempDir, gobinPath), WithGroups("ci"))), 0)
+}
+
+func Test_resolveCmdPath(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	gobinPath := filepath.Join(tempDir, minlib.GobinDirBase)
+
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(`
+github.com/hairyhenderson/gomplate/v4/cmd/gomplate@latest tags=foo
+github.com/sqlc-dev/sqlc/cmd/sqlc@latest
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, maniLockBase), []byte(`
+github.com/hairyhenderson/gomplate/v4/cmd/gomplate@v4.1.0
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, goModBase), []byte(`module example.com/foo
+
+go 1.23
+
+require golang.org/x/tools v0.24.0
+`), 0644))
+	params := newInstallParams()
+
+	cmdPath := V(resolveCmdPath("golang.org/x/tools/cmd/stringer", params, tempDir, gobinPath))
+	assert.Equal(t, minlib.InstalledCmdPath(gobinPath, "golang.org/x/tools/cmd/stringer", "v0.24.0", ""), cmdPath)
+	cmdPath = V(resolveCmdPath("gomplate", params, tempDir, gobinPath))
+	assert.Equal(t, minlib.InstalledCmdPath(gobinPath, "github.com/hairyhenderson/gomplate/v4/cmd/gomplate", "v4.1.0", "foo"), cmdPath)
+	_, err := resolveCmdPath("sqlc", params, tempDir, gobinPath)
+	assert.Error(t, err)
+	_, err = resolveCmdPath("goyacc", params, tempDir, gobinPath)
+	assert.Error(t, err)
+}
+
+func Test_quoteShells(t *testing.T) {
+	assert.Equal(t, `'/home/o'\''neil/.gobin'`, quoteSh("/home/o'neil/.gobin"))
+	assert.Equal(t, `'/home/o\'neil/.gobin'`, quoteFish("/home/o'neil/.gobin"))
+	assert.Equal(t, `'C:\Users\o''neil\.gobin'`, quotePowerShell(`C:\Users\o'neil\.gobin`))
+	assert.Equal(t, strings.Join([]string{"/a", "/b", os.Getenv("PATH")}, string(filepath.ListSeparator)), prependedPath([]string{"/a", "/b"}))
+}
+
+func Test_createShims(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	gobinPath := filepath.Join(tempDir, minlib.GobinDirBase)
+
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(`
+golang.org/x/tools/cmd/stringer@latest
+github.com/sqlc-dev/sqlc/cmd/sqlc@latest os=plan9
+github.com/knaka/gobin/cmd/gobin@latest
+`), 0644))
+	manifest := V(parseManifest(tempDir, withPlatform("linux", "amd64")))
+	shimPaths := V(createShims(manifest.Entries(), gobinPath, minlib.ShimSymlink))
+	assert.Equal(t, []string{filepath.Join(gobinPath, "stringer")}, shimPaths)
+	assert.Equal(t, minlib.GobinCmdBase, V(os.Readlink(shimPaths[0])))
+	// Creating the shims again is harmless.
+	assert.Equal(t, shimPaths, V(createShims(manifest.Entries(), gobinPath, minlib.ShimSymlink)))
+}
+
+func Test_completeTools(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(`
+golang.org/x/tools/cmd/stringer@latest
+github.com/sqlc-dev/sqlc/cmd/sqlc@latest groups=ci
+example.com/foo/cmd/stringer@latest
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, goModBase), []byte(`module example.com/foo
+
+go 1.23
+
+require (
+	github.com/sqlc-dev/sqlc v1.27.0
+	github.com/stretchr/testify v1.9.0
+	golang.org/x/tools v0.24.0
+)
+
+tool golang.org/x/tools/cmd/goyacc
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, "tools.go"), []byte(`//go:build tools
+
+package main
+
+import _ "github.com/sqlc-dev/sqlc/cmd/sqlc"
+`), 0644))
+	params := newInstallParams()
+	assert.Equal(t, []string{"sqlc", "stringer"}, V(completeTools("s", params, tempDir)))
+	// The base name resolves to the last entry.
+	assert.Equal(t, []string{
+		"example.com/foo/cmd/stringer",
+		"github.com/sqlc-dev/sqlc/cmd/sqlc",
+		"golang.org/x/tools/cmd/goyacc",
+		"golang.org/x/tools/cmd/stringer",
+		"sqlc",
+		"stringer",
+	}, V(completeTools("", params, tempDir)))
+	params.groups = []string{"ci"}
+	// The libraries required in go.mod, e.g. testify, are not offered.
+	assert.Equal(t, []string{"github.com/sqlc-dev/sqlc/cmd/sqlc"}, V(completeTools("github.com/", params, tempDir)))
+}
+
+func Test_toolsGoEntries(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	V0(os.WriteFile(filepath.Join(tempDir, "tools.go"), []byte(`//go:build tools
+
+package main
+
+import (
+	_ "github.com/sqlc-dev/sqlc/cmd/sqlc"
+	_ "golang.org/x/tools/cmd/stringer"
+	"fmt"
+)
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, "main.go"), []byte(`//go:build !tools
+
+package main
+
+import _ "golang.org/x/tools/cmd/goyacc"
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, goModBase), []byte(`module example.com/foo
+
+go 1.23
+
+require golang.org/x/tools v0.24.0
+`), 0644))
+	entries := V(toolsGoEntries(tempDir))
+	assert.Equal(t, []string{"github.com/sqlc-dev/sqlc/cmd/sqlc@latest", "golang.org/x/tools/cmd/stringer@v0.24.0"},
+		lo.Map(entries, func(entry *maniEntry, _ int) string { return entry.Pkg + "@" + entry.Version }))
+}
+
+func Test_gobinEntries(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	srcDir := filepath.Join(tempDir, "src")
+	binDir := filepath.Join(tempDir, "bin")
+	V0(os.MkdirAll(filepath.Join(srcDir, "cmd", "hello"), 0755))
+	V0(os.WriteFile(filepath.Join(srcDir, goModBase), []byte("module example.com/hello\n\ngo 1.23\n"), 0644))
+	V0(os.WriteFile(filepath.Join(srcDir, "cmd", "hello", "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+	cmd := exec.Command("go", "build", "-o", filepath.Join(binDir, "hello"), "./cmd/hello")
+	cmd.Dir = srcDir
+	V0(cmd.Run())
+	// Binaries cached by gobin with versioned names are skipped.
+	V0(os.WriteFile(filepath.Join(binDir, "stringer@v0.24.0"), []byte("not a binary"), 0755))
+	entries := V(gobinEntries(binDir))
+	assert.Equal(t, []string{"example.com/hello/cmd/hello@latest"},
+		lo.Map(entries, func(entry *maniEntry, _ int) string { return entry.Pkg + "@" + entry.Version }))
+}
+
+func TestInitProject(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	V0(os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("/vendor"), 0644))
+	filePaths := V(InitProject(WithDir(tempDir)))
+	assert.Equal(t, []string{
+		filepath.Join(tempDir, maniBase),
+		filepath.Join(tempDir, "cmd-gobin"),
+		filepath.Join(tempDir, "cmd-gobin.cmd"),
+		filepath.Join(tempDir, "cmd-gobin.go"),
+		filepath.Join(tempDir, ".gitignore"),
+	}, filePaths)
+	assert.Equal(t, "/vendor\n/.gobin/\n", string(V(os.ReadFile(filepath.Join(tempDir, ".gitignore")))))
+	assert.Equal(t, os.FileMode(0755), V(os.Stat(filepath.Join(tempDir, "cmd-gobin"))).Mode().Perm())
+	// The existing manifest file and “.gitignore” are kept.
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte("golang.org/x/tools/cmd/stringer@v0.24.0\n"), 0644))
+	assert.Empty(t, V(InitProject(WithDir(tempDir), Bootstrap(false))))
+	assert.Equal(t, "golang.org/x/tools/cmd/stringer@v0.24.0\n", string(V(os.ReadFile(filepath.Join(tempDir, maniBase)))))
+}
+
+func Test_migrateToolsGo(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	V0(os.MkdirAll(filepath.Join(tempDir, "tools"), 0755))
+	V0(os.WriteFile(filepath.Join(tempDir, "tools", "tools.go"), []byte(`//go:build tools
+
+package tools
+
+import (
+	_ "github.com/sqlc-dev/sqlc/cmd/sqlc"
+	_ "golang.org/x/tools/cmd/stringer"
+	_ "example.com/lib"
+)
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, "gen.go"), []byte(`//go:build tools
+
+package main
+
+import (
+	"embed"
+	_ "golang.org/x/tools/cmd/stringer"
+)
+
+var _ embed.FS
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, goModBase), []byte(`module example.com/foo
+
+go 1.23
+
+require golang.org/x/tools v0.24.0
+`), 0644))
+	// The comments, the section header and the layout of the existing manifest are kept.
+	existing := "# Tools of the project.\n[dev]\n#: Generates String() methods.\ngolang.org/x/tools/cmd/stringer@latest   tags=foo"
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(existing), 0644))
+	// The go command which tells the names of the packages as “go list” does.
+	goCmdPath := filepath.Join(tempDir, "go")
+	V0(os.WriteFile(goCmdPath, []byte(`#!/bin/sh
+shift 4
+for pkg in "$@"; do
+	case "$pkg" in
+	*/cmd/*) echo "$pkg main" ;;
+	*) echo "$pkg lib" ;;
+	esac
+done
+`), 0755))
+	var logs bytes.Buffer
+	params := newInstallParams()
+	params.optLogger = stdlog.New(&logs, "", 0)
+	params.goCmdPath = goCmdPath
+	pkgs := V(migrateToolsGo(params, tempDir))
+	// The library imported for its side effects is not a tool.
+	assert.Contains(t, logs.String(), "Skipping example.com/lib which is not a main package")
+	assert.Equal(t, []string{"golang.org/x/tools/cmd/stringer", "github.com/sqlc-dev/sqlc/cmd/sqlc"}, pkgs)
+	assert.Equal(t, existing+"\n[]\ngithub.com/sqlc-dev/sqlc/cmd/sqlc@latest\n",
+		string(V(os.ReadFile(filepath.Join(tempDir, maniBase)))))
+	assert.Contains(t, logs.String(), "Added 1 tool(s)")
+	manifest := V(parseManifest(tempDir))
+	assert.Equal(t, []string{"dev"}, manifest.lookup("stringer").Groups)
+	assert.Equal(t, "Generates String() methods.", manifest.lookup("stringer").Description)
+	assert.Empty(t, manifest.lookup("sqlc").Groups)
+	assert.Equal(t, "golang.org/x/tools/cmd/stringer@v0.24.0\n",
+		string(V(os.ReadFile(filepath.Join(tempDir, maniLockBase)))))
+
+	for _, file := range V(toolsGoFiles(tempDir)) {
+		assert.False(t, V(file.removeImports(pkgs)))
+	}
+	assert.NotContains(t, string(V(os.ReadFile(filepath.Join(tempDir, "gen.go")))), "stringer")
+	// The file is kept with the import of the library, which is not migrated.
+	files := V(toolsGoFiles(tempDir))
+	assert.Empty(t, files[0].imports)
+	assert.Equal(t, []string{"example.com/lib"}, files[1].imports)
+	assert.True(t, V(files[1].removeImports([]string{"example.com/lib"})))
+	assert.NoFileExists(t, filepath.Join(tempDir, "tools", "tools.go"))
+}
+
+func Test_exportManifest(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	V0(os.WriteFile(filepath.Join(tempDir, goModBase), []byte(`module example.com/foo
+
+go 1.24
+
+require golang.org/x/tools v0.24.0
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, maniTOMLBase), []byte(`[tools.stringer]
+pkg = "golang.org/x/tools/cmd/stringer"
+version = "latest"
+
+[tools.sqlc]
+pkg = "github.com/sqlc-dev/sqlc/cmd/sqlc"
+version = "latest"
+tags = ["foo"]
+ldflags = "-s -w"
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, maniLockBase), []byte("github.com/sqlc-dev/sqlc/cmd/sqlc@v1.27.0\n"), 0644))
+	// The module of sqlc is found in the module cache without querying the module proxy.
+	modCachePath := filepath.Join(tempDir, "modcache")
+	t.Setenv("GOMODCACHE", modCachePath)
+	V0(os.MkdirAll(filepath.Join(modCachePath, "cache", "download", "github.com", "sqlc-dev", "sqlc", "@v"), 0755))
+	V0(os.WriteFile(filepath.Join(modCachePath, "cache", "download", "github.com", "sqlc-dev", "sqlc", "@v", "v1.27.0.mod"), []byte("module github.com/sqlc-dev/sqlc\n"), 0644))
+
+	export := func(format string) string {
+		buf := &bytes.Buffer{}
+		params := newInstallParams()
+		params.stdout = buf
+		V0(exportManifest(format, params, tempDir))
+		return buf.String()
+	}
+	goTool := export(ExportFormatGoTool)
+	assert.Contains(t, goTool, "require (\n\tgolang.org/x/tools v0.24.0\n\tgithub.com/sqlc-dev/sqlc v1.27.0\n)\n")
+	assert.Contains(t, goTool, "tool (\n\tgolang.org/x/tools/cmd/stringer\n\tgithub.com/sqlc-dev/sqlc/cmd/sqlc\n)\n")
+
+	toolsGoPath := filepath.Join(tempDir, "tools.go")
+	V0(os.WriteFile(toolsGoPath, []byte(export(ExportFormatToolsGo)), 0644))
+	file := parseToolsGoFile(toolsGoPath)
+	assert.NotNil(t, file)
+	assert.Equal(t, []string{"golang.org/x/tools/cmd/stringer", "github.com/sqlc-dev/sqlc/cmd/sqlc"}, file.imports)
+	assert.Contains(t, string(V(os.ReadFile(toolsGoPath))), "//\tgo get golang.org/x/tools/cmd/stringer@v0.24.0\n")
+
+	assert.Contains(t, export(ExportFormatShell), "\ngo install golang.org/x/tools/cmd/stringer@v0.24.0\ngo install -tags 'foo' -ldflags '-s -w' github.com/sqlc-dev/sqlc/cmd/sqlc@v1.27.0\n")
+
+	assert.Error(t, exportManifest("yaml", newInstallParams(), tempDir))
+
+	// The entries not locked yet fail the export instead of being exported as “latest”.
+	V0(os.Remove(filepath.Join(tempDir, maniLockBase)))
+	err := exportManifest(ExportFormatShell, newInstallParams(), tempDir)
+	assert.ErrorContains(t, err, "the versions of github.com/sqlc-dev/sqlc/cmd/sqlc are not locked yet")
+}
+
+func Test_generateDirective(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	cacheDirPath := filepath.Join(tempDir, minlib.GobinDirBase)
+	V0(os.WriteFile(filepath.Join(tempDir, "gen.go"), []byte(`package foo
+
+//go:generate_input ./sqlc.yaml
+//go:generate_input ./schema*.sql
+//go:generate_output ./sqlcgen/models.go
+//go:generate sqlc generate
+
+//go:generate stringer -type Fruit .
+`), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, "sqlc.yaml"), []byte("version: 2\n"), 0644))
+	V0(os.WriteFile(filepath.Join(tempDir, "schema1.sql"), []byte("CREATE TABLE foo (id INT);\n"), 0644))
+	inputs, outputs := V2(parseGenerateAnnotations(filepath.Join(tempDir, "gen.go"), 6))
+	assert.Equal(t, []string{"./sqlc.yaml", "./schema*.sql"}, inputs)
+	assert.Equal(t, []string{"./sqlcgen/models.go"}, outputs)
+
+	sqlcPath := filepath.Join(cacheDirPath, "sqlc@v1.27.0")
+	newDirective := func() *GenerateDirective {
+		return V(newGenerateDirective(cacheDirPath, tempDir, "gen.go", 6, sqlcPath, []string{"generate"}))
+	}
+	directive := newDirective()
+	assert.False(t, V(directive.IsFresh()))
+	V0(directive.Record())
+	// Not fresh until the output exists.
+	assert.False(t, V(newDirective().IsFresh()))
+	V0(os.MkdirAll(filepath.Join(tempDir, "sqlcgen"), 0755))
+	V0(os.WriteFile(filepath.Join(tempDir, "sqlcgen", "models.go"), []byte("package sqlcgen\n"), 0644))
+	assert.True(t, V(newDirective().IsFresh()))
+
+	// A new input file, another version of the tool or other arguments make the directive stale.
+	V0(os.WriteFile(filepath.Join(tempDir, "schema2.sql"), []byte("CREATE TABLE bar (id INT);\n"), 0644))
+	assert.False(t, V(newDirective().IsFresh()))
+	V0(newDirective().Record())
+	assert.True(t, V(newDirective().IsFresh()))
+	assert.False(t, V(V(newGenerateDirective(cacheDirPath, tempDir, "gen.go", 6, filepath.Join(cacheDirPath, "sqlc@v1.28.0"), []string{"generate"})).IsFresh()))
+	assert.False(t, V(V(newGenerateDirective(cacheDirPath, tempDir, "gen.go", 6, sqlcPath, []string{"vet"})).IsFresh()))
+
+	// A corrupt cache makes the directive stale and is rewritten by Record.
+	V0(os.WriteFile(filepath.Join(cacheDirPath, generateCacheBase), []byte("{"), 0644))
+	assert.False(t, V(newDirective().IsFresh()))
+	V0(newDirective().Record())
+	assert.True(t, V(newDirective().IsFresh()))
+
+	// The directive without the input annotation always runs.
+	assert.Nil(t, V(newGenerateDirective(cacheDirPath, tempDir, "gen.go", 8, filepath.Join(cacheDirPath, "stringer@v0.24.0"), nil)))
+}
+
+func Test_depGraph(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(`example.com/cmd/gen@latest requires=lint,fmt
+example.com/cmd/lint@v1.0.0 requires=fmt
+example.com/cmd/fmt@v2.0.0
+example.com/cmd/other@latest requires=undefined
+`), 0644))
+	params := newInstallParams()
+	graph := V(newDepGraph(params, tempDir))
+	assert.Equal(t, []string{"fmt", "lint", "example.com/cmd/gen"}, V(graph.sort([]string{"example.com/cmd/gen"})))
+	assert.Equal(t, []string{"example.com/cmd/gen", "example.com/cmd/other"}, graph.rootTargets(nil))
+
+	roots := V(depTree([]string{"gen"}, params, tempDir))
+	assert.Equal(t, []*DepNode{{
+		Name:    "gen",
+		Pkg:     "example.com/cmd/gen",
+		Version: "latest",
+		Requires: []*DepNode{
+			{Name: "lint", Pkg: "example.com/cmd/lint", Version: "v1.0.0", Requires: []*DepNode{
+				{Name: "fmt", Pkg: "example.com/cmd/fmt", Version: "v2.0.0"},
+			}},
+			{Name: "fmt", Pkg: "example.com/cmd/fmt", Version: "v2.0.0"},
+		},
+	}}, roots)
+
+	assert.Equal(t, [][]string{
+		{"example.com/cmd/gen", "example.com/cmd/fmt"},
+		{"example.com/cmd/gen", "example.com/cmd/lint", "example.com/cmd/fmt"},
+	}, V(why("fmt", params, tempDir)))
+	assert.Empty(t, V(why("gen", params, tempDir)))
+	assert.Equal(t, [][]string{{"example.com/cmd/other", "undefined"}}, V(why("undefined", params, tempDir)))
+	_, err := why("nothing", params, tempDir)
+	assert.Error(t, err)
+
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(`example.com/cmd/gen@latest requires=lint
+example.com/cmd/lint@v1.0.0 requires=fmt
+example.com/cmd/fmt@v2.0.0 requires=gen
+`), 0644))
+	_, err = newDepGraph(params, tempDir)
+	assert.ErrorContains(t, err, "example.com/cmd/gen -> example.com/cmd/lint -> example.com/cmd/fmt -> example.com/cmd/gen")
+	// A cycle fails the installation before anything is installed, instead of looping forever.
+	_, err = (func() (cmdPath string, err error) {
+		defer Catch(&err)
+		return install([]string{"lint"}, params, tempDir, filepath.Join(tempDir, minlib.GobinDirBase))
+	})()
+	assert.ErrorContains(t, err, "cycle")
+}
+
+func Test_requiredVersions(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	params := newInstallParams()
+	writeManifest := func(content string) {
+		V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(content), 0644))
+	}
+
+	writeManifest(`example.com/cmd/gen@latest requires=stringer@v0.23.0,sh
+example.com/cmd/lint@latest requires=golang.org/x/tools/cmd/stringer@v0.23.0
+golang.org/x/tools/cmd/stringer@latest
+`)
+	graph := V(newDepGraph(params, tempDir))
+	assert.Equal(t, map[string]string{"golang.org/x/tools/cmd/stringer": "v0.23.0"}, V(graph.requiredVersions()))
+	// A command which is not defined but found in PATH is a leaf of the tree.
+	roots := V(depTree([]string{"gen"}, params, tempDir))
+	assert.Equal(t, "stringer@v0.23.0", roots[0].Requires[0].Name)
+	assert.Equal(t, "golang.org/x/tools/cmd/stringer", roots[0].Requires[0].Pkg)
+	assert.Equal(t, "sh", roots[0].Requires[1].Name)
+	assert.Equal(t, V(exec.LookPath("sh")), roots[0].Requires[1].Path)
+
+	// Conflicts with another requirement.
+	writeManifest(`example.com/cmd/gen@latest requires=stringer@v0.23.0
+example.com/cmd/lint@latest requires=stringer@v0.24.0
+golang.org/x/tools/cmd/stringer@latest
+`)
+	_, err := newDepGraph(params, tempDir)
+	assert.ErrorContains(t, err, "example.com/cmd/lint requires stringer@v0.24.0, which conflicts with stringer@v0.23.0 required by example.com/cmd/gen")
+
+	// Conflicts with the locked version of the entry.
+	writeManifest(`example.com/cmd/gen@latest requires=stringer@v0.23.0
+golang.org/x/tools/cmd/stringer@latest
+`)
+	V0(os.WriteFile(filepath.Join(tempDir, maniLockBase), []byte("golang.org/x/tools/cmd/stringer@v0.24.0\n"), 0644))
+	_, err = newDepGraph(params, tempDir)
+	assert.ErrorContains(t, err, "which conflicts with the version v0.24.0 of golang.org/x/tools/cmd/stringer in the manifest")
+	V0(os.Remove(filepath.Join(tempDir, maniLockBase)))
+
+	// Conflicts with go.mod.
+	writeManifest("example.com/cmd/gen@latest requires=golang.org/x/tools/cmd/stringer@v0.23.0\n")
+	V0(os.WriteFile(filepath.Join(tempDir, goModBase), []byte("module example.com/foo\n\ngo 1.23\n\nrequire golang.org/x/tools v0.24.0\n"), 0644))
+	_, err = newDepGraph(params, tempDir)
+	assert.ErrorContains(t, err, "which conflicts with the version v0.24.0 of golang.org/x/tools in go.mod")
+	V0(os.Remove(filepath.Join(tempDir, goModBase)))
+
+	// A required command which is neither defined nor in PATH fails the installation before the one which requires it is installed.
+	writeManifest("example.com/cmd/gen@v1.0.0 requires=no-such-command-of-gobin-test\n")
+	_, err = (func() (cmdPath string, err error) {
+		defer Catch(&err)
+		return install([]string{"gen"}, params, tempDir, filepath.Join(tempDir, minlib.GobinDirBase))
+	})()
+	assert.ErrorContains(t, err, "required command “no-such-command-of-gobin-test” is neither defined in the manifest nor found in PATH")
+
+	// The version of a command looked up in PATH cannot be enforced.
+	writeManifest("example.com/cmd/gen@v1.0.0 requires=protoc@v3.21.0\n")
+	_, err = newDepGraph(params, tempDir)
+	assert.ErrorContains(t, err, "example.com/cmd/gen requires protoc@v3.21.0, but the version cannot be specified for protoc")
+
+	// The command required by the base name with a version is installed and locked at the version, not looked up in PATH.
+	writeManifest("example.com/cmd/gen@v1.0.0 requires=stringer@v0.23.0\ngolang.org/x/tools/cmd/stringer@latest\n")
+	gobinPath := filepath.Join(tempDir, minlib.GobinDirBase)
+	V0(os.MkdirAll(gobinPath, 0755))
+	for _, cmdPath := range []string{
+		minlib.InstalledCmdPath(gobinPath, "example.com/cmd/gen", "v1.0.0", ""),
+		minlib.InstalledCmdPath(gobinPath, "golang.org/x/tools/cmd/stringer", "v0.23.0", ""),
+	} {
+		V0(os.WriteFile(cmdPath, []byte("binary"), 0755))
+	}
+	t.Setenv("PATH", tempDir)
+	V0(os.WriteFile(filepath.Join(tempDir, "stringer"), []byte("#!/bin/sh\n"), 0755))
+	cmdPath := V(install([]string{"gen"}, params, tempDir, gobinPath))
+	assert.Equal(t, minlib.InstalledCmdPath(gobinPath, "example.com/cmd/gen", "v1.0.0", ""), cmdPath)
+	assert.Contains(t, string(V(os.ReadFile(filepath.Join(tempDir, maniLockBase)))), "golang.org/x/tools/cmd/stringer@v0.23.0")
+	V0(os.Remove(filepath.Join(tempDir, maniLockBase)))
+
+	writeManifest("example.com/cmd/gen@v1.0.0 requires=stringer@latest-ish\n")
+	_, err = newDepGraph(params, tempDir)
+	assert.ErrorContains(t, err, "invalid version “latest-ish” of required command “stringer”")
+}
+
+func TestInstaller(t *testing.T) {
+	var confDirPaths []string
+	for _, content := range []string{
+		"example.com/cmd/foo@v1.0.0 os=plan9\n",
+		"example.com/cmd/bar@v1.0.0 os=plan9\n",
+	} {
+		tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+		t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+		V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(content), 0644))
+		confDirPaths = append(confDirPaths, tempDir)
+	}
+	var buffers [2]bytes.Buffer
+	var installers []*Installer
+	for i, confDirPath := range confDirPaths {
+		installers = append(installers, &Installer{
+			ConfDirPath: confDirPath,
+			Logger:      stdlog.New(&buffers[i], "", 0),
+		})
+	}
+
+	// The installers run in parallel do not share the configuration directory or the logger.
+	pkgs := []string{"example.com/cmd/foo", "example.com/cmd/bar"}
+	var waitGroup sync.WaitGroup
+	for i, installer := range installers {
+		waitGroup.Add(1)
+		go (func() {
+			defer waitGroup.Done()
+			entries, err := installer.ListEx()
+			if assert.NoError(t, err) && assert.Len(t, entries, 1) {
+				assert.Equal(t, pkgs[i], entries[0].Pkg)
+				assert.Equal(t, filepath.Join(confDirPaths[i], minlib.GobinDirBase), filepath.Dir(entries[0].BinPath))
+			}
+			cmdPath, err := installer.InstallEx([]string{path.Base(pkgs[i])})
+			assert.NoError(t, err)
+			assert.Empty(t, cmdPath)
+		})()
+	}
+	waitGroup.Wait()
+	for i, pkg := range pkgs {
+		assert.Equal(t, "Skipping "+pkg+" which is not applicable to the platform\n", buffers[i].String())
+		buffers[i].Reset()
+	}
+
+	// The options passed to the methods take precedence over the fields.
+	V(installers[1].InstallEx([]string{"bar"}, Silent(true)))
+	assert.Empty(t, buffers[1].String())
+	V(installers[1].InstallEx([]string{"bar"}))
+	assert.Contains(t, buffers[1].String(), "example.com/cmd/bar")
+
+	// The other operations also take the configuration directory of the installer.
+	assert.Equal(t, "example.com/cmd/foo", V(installers[0].Lookup("foo")).Pkg)
+	assert.Equal(t, []string{"foo"}, V(installers[0].CompleteToolsEx("f")))
+	assert.Equal(t, "example.com/cmd/bar", V(installers[1].DepTreeEx(nil))[0].Pkg)
+	_, err := installers[1].WhyEx("foo")
+	assert.ErrorIs(t, err, ErrToolNotDefined)
+
+	// The go command of the installer is put on PATH in place of the managed Go SDK.
+	goCmdPath := filepath.Join(confDirPaths[0], "bin", "go")
+	installers[0].GoCmdPath = goCmdPath
+	assert.Equal(t, []string{
+		filepath.Join(confDirPaths[0], minlib.GobinDirBase),
+		filepath.Dir(goCmdPath),
+	}, V(installers[0].PathDirsEx()))
+	assert.Contains(t, V(installers[0].EnvScriptEx(ShellSh)), quoteSh(filepath.Dir(goCmdPath)))
+}
+
+func TestInstallContext(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte("example.com/cmd/foo@v1.0.0\nexample.com/cmd/bar@latest\n"), 0644))
+	// The go command which hangs as a long build.
+	goCmdPath := filepath.Join(tempDir, "go")
+	V0(os.WriteFile(goCmdPath, []byte("#!/bin/sh\nexec sleep 10\n"), 0755))
+	installer := &Installer{ConfDirPath: tempDir, GoCmdPath: goCmdPath}
+
+	for _, name := range []string{"foo", "bar"} {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		start := time.Now()
+		_, err := installer.InstallContext(ctx, []string{name}, Silent(true))
+		cancel()
+		assert.ErrorIs(t, err, context.DeadlineExceeded, name)
+		assert.Less(t, time.Since(start), 5*time.Second, name)
+	}
+	// The version of the cancelled query is not locked.
+	_, err := os.Stat(filepath.Join(tempDir, maniLockBase))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestPathDirsContext(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// The resolution of the managed Go SDK is cancelled with the context.
+	_, err := PathDirsEx(WithConfDir(tempDir, ""), WithContext(ctx))
+	assert.ErrorIs(t, err, context.Canceled)
+	params := newInstallParams()
+	V0(WithContext(ctx)(params))
+	_, err = generateCommand(nil, params, tempDir)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestErrors(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	V0(os.WriteFile(filepath.Join(tempDir, maniBase), []byte(`example.com/a/cmd/gen@v1.0.0
+example.com/b/cmd/gen@v1.0.0
+example.com/cmd/foo@v1.0.0
+`), 0644))
+	goCmdPath := filepath.Join(tempDir, "go")
+	V0(os.WriteFile(goCmdPath, []byte("#!/bin/sh\necho 'build failed' >&2\nexit 1\n"), 0755))
+	installer := &Installer{ConfDirPath: tempDir, GoCmdPath: goCmdPath}
+
+	_, err := installer.WhichEx("gen")
+	assert.ErrorIs(t, err, ErrAmbiguousTool)
+	assert.ErrorContains(t, err, "example.com/a/cmd/gen, example.com/b/cmd/gen")
+	_, err = installer.WhichEx("example.com/b/cmd/gen", InstallIfMissing(false))
+	assert.NotErrorIs(t, err, ErrAmbiguousTool)
+	_, err = installer.InstallEx([]string{"bar"})
+	assert.ErrorIs(t, err, ErrToolNotDefined)
+	assert.ErrorContains(t, err, "command “bar” is not defined")
+	err = installer.UpdateEx([]string{"bar"})
+	assert.ErrorIs(t, err, ErrToolNotDefined)
+
+	// The output of the build goes to the writer and is attached to the error.
+	var stderr bytes.Buffer
+	_, err = installer.InstallEx([]string{"foo"}, Silent(true), WithStderr(&stderr))
+	var installErr *InstallError
+	if assert.ErrorAs(t, err, &installErr) {
+		assert.Equal(t, "example.com/cmd/foo", installErr.Pkg)
+		assert.Equal(t, "v1.0.0", installErr.Version)
+		assert.Equal(t, "build failed\n", installErr.Output)
+	}
+	assert.Equal(t, "build failed\n", stderr.String())
+}
+
+func Test_generateCommand(t *testing.T) {
+	tempDir := V(canonAbs(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	// The fake managed Go SDK prints what the directives would see.
+	t.Setenv("HOME", tempDir)
+	sdkBinPath := filepath.Join(tempDir, "sdk", "go1.23.1", "bin")
+	V0(os.MkdirAll(sdkBinPath, 0755))
+	V0(os.WriteFile(filepath.Join(sdkBinPath, "go"), []byte("#!/bin/sh\necho \"$@\"\necho \"$PATH\"\necho \"$GOBIN_GENERATE_CACHE\"\necho \"$FOO\"\n"), 0755))
+	gobinPath := filepath.Join(tempDir, minlib.GobinDirBase)
+
+	var stdout bytes.Buffer
+	params := newInstallParams()
+	V0(WithStdout(&stdout)(params))
+	V0(WithEnv([]string{"FOO=bar"})(params))
+	cmd := V(generateCommand([]string{"-run", "sqlc", "./..."}, params, gobinPath))
+	assert.Equal(t, filepath.Join(sdkBinPath, "go"), cmd.Path)
+	V0(cmd.Run())
+	lines := strings.Split(stdout.String(), "\n")
+	assert.Equal(t, "generate -run sqlc ./...", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], gobinPath+string(filepath.ListSeparator)+sdkBinPath+string(filepath.ListSeparator)), lines[1])
+	assert.Equal(t, gobinPath, lines[2])
+	assert.Equal(t, "bar", lines[3])
+
+	// WithGoCmdPath takes precedence over the managed Go SDK.
+	V0(WithGoCmdPath("/usr/local/go/bin/go")(params))
+	cmd = V(generateCommand(nil, params, gobinPath))
+	assert.Equal(t, "/usr/local/go/bin/go", cmd.Path)
+}
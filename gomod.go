@@ -7,6 +7,7 @@ import (
 	"golang.org/x/mod/module"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -16,6 +17,8 @@ const goModBase = "go.mod"
 type goModDefT struct {
 	name            string
 	requiredModules []*module.Version
+	// toolPkgs are the packages of the “tool” directives, which are main packages.
+	toolPkgs []string
 }
 
 // requiredModule returns the required module if it exists.
@@ -59,6 +62,25 @@ func parseGoMod(dirPath string) (goModDef *goModDefT, err error) {
 		requiredModules: lo.Map(goModFile.Require, func(reqMod *modfile.Require, _ int) *module.Version {
 			return &reqMod.Mod
 		}),
+		toolPkgs: lo.Map(goModFile.Tool, func(tool *modfile.Tool, _ int) string {
+			return tool.Path
+		}),
+	}
+	return
+}
+
+// toolPkgsOf returns the main packages whose modules go.mod requires, which are the ones of the “tool” directives and of the blank imports in the “tools.go” files in the directory, each once.
+func (mod *goModDefT) toolPkgsOf(dirPath string) (pkgs []string, err error) {
+	defer Catch(&err)
+	candidates := slices.Clone(mod.toolPkgs)
+	for _, file := range V(toolsGoFiles(dirPath)) {
+		candidates = append(candidates, file.imports...)
+	}
+	for _, pkg := range candidates {
+		// Only the packages which install resolves to a version in go.mod.
+		if mod.requiredModuleByPkg(pkg) != nil && !slices.Contains(pkgs, pkg) {
+			pkgs = append(pkgs, pkg)
+		}
 	}
 	return
 }
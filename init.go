@@ -0,0 +1,169 @@
+package gobin
+
+import (
+	"bufio"
+	"debug/buildinfo"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	. "github.com/knaka/go-utils"
+	"github.com/knaka/gobin/minlib"
+)
+
+// bootstrapFS holds the bootstrap scripts generated by task-project.lib.sh, which install and run the gobin command of the locked version without gobin installed.
+//
+//go:embed bootstrap/cmd-gobin.go bootstrap/cmd-gobin bootstrap/cmd-gobin.cmd
+var bootstrapFS embed.FS
+
+const bootstrapDir = "bootstrap"
+
+// Sources of the entries of the manifest file which InitProject creates.
+const (
+	// SeedToolsGo seeds the entries from the blank imports of the “tools.go” files, with the versions in go.mod.
+	SeedToolsGo = "tools-go"
+	// SeedGobin seeds the entries from the binaries in the global gobin directory ($GOBIN or ~/go/bin), with the versions they were built from.
+	SeedGobin = "gobin"
+)
+
+// WithSeed sets the source of the entries of the manifest file which InitProject creates.
+//
+//goland:noinspection GoUnusedExportedFunction
+func WithSeed(seed string) Option {
+	return func(params *installParams) (err error) {
+		params.seed = seed
+		return
+	}
+}
+
+// Bootstrap sets whether InitProject writes the bootstrap scripts. The default is true.
+//
+//goland:noinspection GoUnusedExportedFunction
+func Bootstrap(f bool) Option {
+	return func(params *installParams) (err error) {
+		params.optBootstrap = P(f)
+		return
+	}
+}
+
+// toolsGoEntries returns the entries of the packages imported by the “tools.go” files in the directory. The versions come from go.mod, or are “latest” if not required there.
+func toolsGoEntries(dirPath string) (entries []*maniEntry, err error) {
+	defer Catch(&err)
+	goModDef := V(parseGoMod(dirPath))
+	for _, file := range V(toolsGoFiles(dirPath)) {
+		for _, pkg := range file.imports {
+			entry := &maniEntry{Pkg: pkg, Version: latestVer}
+			if goModDef != nil {
+				if reqMod := goModDef.requiredModuleByPkg(pkg); reqMod != nil {
+					entry.Version = reqMod.Version
+				}
+			}
+			entries = mergeEntries(entries, entry)
+		}
+	}
+	return
+}
+
+// gobinEntries returns the entries of the binaries in the directory, read from the build information embedded in them. The binaries without the information, built from local sources or cached by gobin with versioned names are skipped.
+func gobinEntries(dirPath string) (entries []*maniEntry, err error) {
+	defer Catch(&err)
+	dirEntries, err_ := os.ReadDir(dirPath)
+	if errors.Is(err_, os.ErrNotExist) {
+		return
+	}
+	V0(err_)
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || strings.Contains(dirEntry.Name(), "@") {
+			continue
+		}
+		info, err_ := buildinfo.ReadFile(filepath.Join(dirPath, dirEntry.Name()))
+		if err_ != nil || info.Path == "" || info.Main.Path == "" || path.Base(info.Path) == minlib.GobinCmdBase {
+			continue
+		}
+		version := info.Main.Version
+		if version == "" || version == "(devel)" {
+			version = latestVer
+		}
+		entries = mergeEntries(entries, &maniEntry{Pkg: info.Path, Version: version})
+	}
+	slices.SortFunc(entries, func(a, b *maniEntry) int { return strings.Compare(a.Pkg, b.Pkg) })
+	return
+}
+
+// ensureGitignored appends the pattern to the “.gitignore” file in the directory unless it is listed already. It returns true if the file is modified.
+func ensureGitignored(dirPath string, pattern string) (modified bool, err error) {
+	defer Catch(&err)
+	filePath := filepath.Join(dirPath, ".gitignore")
+	content, err_ := os.ReadFile(filePath)
+	if err_ != nil && !errors.Is(err_, os.ErrNotExist) {
+		return false, err_
+	}
+	bare := strings.Trim(pattern, "/")
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		if strings.Trim(strings.TrimSpace(scanner.Text()), "/") == bare {
+			return
+		}
+	}
+	if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+		content = append(content, '\n')
+	}
+	content = append(content, []byte(pattern+"\n")...)
+	V0(os.WriteFile(filePath, content, 0644))
+	return true, nil
+}
+
+// writeBootstrapScripts writes the bootstrap scripts into the directory.
+func writeBootstrapScripts(dirPath string) (filePaths []string, err error) {
+	defer Catch(&err)
+	for _, dirEntry := range V(bootstrapFS.ReadDir(bootstrapDir)) {
+		content := V(bootstrapFS.ReadFile(bootstrapDir + "/" + dirEntry.Name()))
+		// The shell script is executable. The others are run by “go run” or by cmd.exe.
+		mode := Ternary[os.FileMode](filepath.Ext(dirEntry.Name()) == "", 0755, 0644)
+		filePath := filepath.Join(dirPath, dirEntry.Name())
+		V0(os.WriteFile(filePath, content, mode))
+		V0(os.Chmod(filePath, mode))
+		filePaths = append(filePaths, filePath)
+	}
+	return
+}
+
+// InitProject sets up the directory (WithDir, or the current directory) to use gobin: creates the manifest file seeded by WithSeed unless a manifest file exists, writes the bootstrap scripts unless Bootstrap(false), and adds the gobin directory to “.gitignore”. It returns the paths of the files created or modified.
+func InitProject(opts ...Option) (filePaths []string, err error) {
+	defer Catch(&err)
+	params := newInstallParams()
+	for _, opt := range opts {
+		V0(opt(params))
+	}
+	dirPath := V(filepath.Abs(Elvis(params.Dir, ".")))
+	if maniPath := V(manifestFilePath(dirPath)); maniPath != "" {
+		params.logger().Printf("Keeping the existing manifest file %s\n", maniPath)
+	} else {
+		var entries []*maniEntry
+		switch params.seed {
+		case "":
+		case SeedToolsGo:
+			entries = V(toolsGoEntries(dirPath))
+		case SeedGobin:
+			_, globalGobinPath := V2(minlib.GlobalConfDirPath())
+			entries = V(gobinEntries(globalGobinPath))
+		default:
+			return nil, errors.New(fmt.Sprintf("unknown seed “%s”", params.seed))
+		}
+		maniPath = filepath.Join(dirPath, maniBase)
+		V0(saveLineManifestFile(&maniFileT{path: maniPath, entries: entries}, maniPath, params))
+		filePaths = append(filePaths, maniPath)
+	}
+	if params.optBootstrap == nil || *params.optBootstrap {
+		filePaths = append(filePaths, V(writeBootstrapScripts(dirPath))...)
+	}
+	if V(ensureGitignored(dirPath, "/"+minlib.GobinDirBase+"/")) {
+		filePaths = append(filePaths, filepath.Join(dirPath, ".gitignore"))
+	}
+	return
+}
@@ -0,0 +1,57 @@
+package gobin
+
+import (
+	stdlog "log"
+)
+
+// Installer holds the settings of the installation, so that the goroutines which install and run commands with different settings do not share any package-level state. The zero value finds the configuration directory from the working directory and logs to the package-level loggers of the log and vlog packages, as the package-level functions do.
+type Installer struct {
+	// ConfDirPath is the directory which has the manifest file. If empty, it is found from the working directory, or is the global one if Global is true.
+	ConfDirPath string
+	// GobinPath is the directory where the binaries are installed. If empty, it is the one in the configuration directory.
+	GobinPath string
+	// Global makes the installer use the global configuration directory.
+	Global bool
+	// Logger is the logger for normal log output.
+	Logger *stdlog.Logger
+	// VerboseLogger is the logger for verbose log output.
+	VerboseLogger *stdlog.Logger
+	// GoCmdPath is the go command which builds and queries the packages. If empty, the one of the managed Go SDK builds them.
+	GoCmdPath string
+	// GoEnv is the environment variables for the go command, e.g. GOPROXY, GOPRIVATE, GOFLAGS and GOTOOLCHAIN.
+	GoEnv []string
+}
+
+// options returns the options which follow the fields. The options passed to the methods are applied after them and take precedence.
+func (installer *Installer) options() (opts []Option) {
+	if installer.ConfDirPath != "" {
+		opts = append(opts, WithConfDir(installer.ConfDirPath, installer.GobinPath))
+	}
+	if installer.Global {
+		opts = append(opts, Global(true))
+	}
+	if installer.Logger != nil {
+		opts = append(opts, WithLogger(installer.Logger))
+	}
+	if installer.VerboseLogger != nil {
+		opts = append(opts, WithVerboseLogger(installer.VerboseLogger))
+	}
+	if installer.GoCmdPath != "" {
+		opts = append(opts, WithGoCmdPath(installer.GoCmdPath))
+	}
+	if installer.GoEnv != nil {
+		opts = append(opts, WithGoEnv(installer.GoEnv))
+	}
+	return
+}
+
+// params returns the parameters of the installer with the options applied.
+func (installer *Installer) params(opts []Option) (params *installParams, err error) {
+	params = newInstallParams()
+	for _, opt := range append(installer.options(), opts...) {
+		if err = opt(params); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
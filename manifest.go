@@ -2,15 +2,26 @@ package gobin
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
 	. "github.com/knaka/go-utils"
 	"github.com/knaka/gobin/minlib"
+	"github.com/samber/lo"
+	"go/build/constraint"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"unicode"
 )
 
 type maniEntry struct {
@@ -19,6 +30,23 @@ type maniEntry struct {
 	LockedVersion string
 	Tags          string
 	Requires      []string
+	// Groups are the names of the groups which the entry belongs to.
+	Groups []string
+	// OSes and Arches restrict the platforms which the entry is applicable to. Empty means any.
+	OSes   []string
+	Arches []string
+	// BuildConstraint is the build-constraint-style expression, e.g. “linux&&(amd64||arm64)”, which the platform should satisfy.
+	BuildConstraint string
+	// Env are the environment variables in the form of “KEY=VALUE” to build the package.
+	Env []string
+	// Ldflags are the flags passed to the linker to build the package.
+	Ldflags string
+	// Description describes the command.
+	Description string
+	// Applicable is true if the entry is applicable to the target platform of the manifest.
+	Applicable bool
+	// Source is the path of the file which defines the entry.
+	Source string
 }
 
 // manifestT is the internal representation of the manifest and the manifest lock file.
@@ -27,87 +55,556 @@ type manifestT struct {
 	entries   []*maniEntry
 	lockPath  string
 	pkgMapVer minlib.PkgVerLockMapT
+	goos      string
+	goarch    string
+}
+
+type manifestOption func(mani *manifestT)
+
+// withPlatform overrides the target platform against which the entries are evaluated. Empty values mean the running platform.
+func withPlatform(goos string, goarch string) manifestOption {
+	return func(mani *manifestT) {
+		mani.goos = Elvis(goos, mani.goos)
+		mani.goarch = Elvis(goarch, mani.goarch)
+	}
 }
 
 const maniBase = "Gobinfile"
+const maniTOMLBase = maniBase + tomlExt
+const tomlExt = ".toml"
 const maniLockBase = "Gobinfile-lock"
 const latestVer = "latest"
 
-var reSpaces = sync.OnceValue(func() *regexp.Regexp { return regexp.MustCompile(`\s+`) })
+// Directives of the manifest file.
+const (
+	// includeDirective includes the entries of the manifest file of the given path. The path is relative to the including file.
+	includeDirective = "include"
+	// inheritDirective merges the entries of the manifest files in the ancestor directories. The entries of the nearer file take precedence.
+	inheritDirective = "inherit"
+)
 
-func parseManifest(dirPath string) (gobinManifest *manifestT, err error) {
-	defer Catch(&err)
-	gobinManifest = &manifestT{
-		filePath: filepath.Join(dirPath, maniBase),
-		lockPath: filepath.Join(dirPath, maniLockBase),
+// docCommentPrefix starts the line of the doc comment which describes the following entry.
+const docCommentPrefix = "#:"
+
+// manifestError is an error at a line of a manifest file.
+type manifestError struct {
+	path string
+	// line is the line number, or 0 if unknown.
+	line int
+	msg  string
+}
+
+func (e *manifestError) Error() string {
+	if e.line == 0 {
+		return fmt.Sprintf("%s: %s", e.path, e.msg)
 	}
-	if _, err_ := os.Stat(gobinManifest.filePath); err_ == nil {
-		reader := V(os.Open(gobinManifest.filePath))
-		defer (func() { V0(reader.Close()) })()
-		scanner := bufio.NewScanner(reader)
-		for scanner.Scan() {
-			line := scanner.Text()
-			line = strings.TrimSpace(line)
-			if line == "" {
+	return fmt.Sprintf("%s:%d: %s", e.path, e.line, e.msg)
+}
+
+// splitManifestLine splits the line of the manifest file into the fields separated by spaces, dropping the comment which starts with “#”. Double-quoted parts can contain spaces and “#”, and are kept quoted in the fields.
+func splitManifestLine(line string) (fields []string, err error) {
+	field := strings.Builder{}
+	inField := false
+	quoted := false
+	escaped := false
+	for _, r := range line {
+		if quoted {
+			field.WriteRune(r)
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == '"':
+				quoted = false
+			}
+			continue
+		}
+		if r == '#' {
+			break
+		}
+		if unicode.IsSpace(r) {
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+			continue
+		}
+		inField = true
+		field.WriteRune(r)
+		if r == '"' {
+			quoted = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quoted value")
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return
+}
+
+// unquoteValue returns the value without the surrounding double quotes, if any.
+func unquoteValue(val string) (string, error) {
+	if !strings.HasPrefix(val, `"`) {
+		return val, nil
+	}
+	return strconv.Unquote(val)
+}
+
+// parseManifestFields parses the fields of a line of the manifest file and returns the entry. The problems found in the line are returned as messages.
+func parseManifestFields(fields []string) (entry *maniEntry, problems []string) {
+	pkgVer := fields[0]
+	var requires []string
+	var tags string
+	var groups []string
+	var oses []string
+	var arches []string
+	var buildConstraint string
+	var description string
+	for _, opt := range fields[1:] {
+		x := strings.SplitN(opt, "=", 2)
+		if len(x) < 2 {
+			problems = append(problems, fmt.Sprintf("malformed option “%s”, which should be “key=value”", opt))
+			continue
+		}
+		key := x[0]
+		val, err := unquoteValue(x[1])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("malformed quoted value of option “%s”", key))
+			continue
+		}
+		switch key {
+		case "requires":
+			reqs := strings.Split(val, ",")
+			for _, req := range reqs {
+				requires = append(requires, req)
+			}
+		case "tags":
+			tags = val
+		case "groups":
+			groups = append(groups, strings.Split(val, ",")...)
+		case "os":
+			oses = append(oses, strings.Split(val, ",")...)
+		case "arch":
+			arches = append(arches, strings.Split(val, ",")...)
+		case "build":
+			buildConstraint = val
+		case "desc":
+			description = val
+		default:
+			problems = append(problems, fmt.Sprintf("unknown option “%s”", key))
+		}
+	}
+	divs := strings.SplitN(pkgVer, "@", 2)
+	pkg := divs[0]
+	ver := TernaryF(len(divs) >= 2,
+		func() string { return divs[1] },
+		func() string { return latestVer },
+	)
+	entry = &maniEntry{
+		Pkg:             pkg,
+		Version:         ver,
+		Tags:            tags,
+		Requires:        requires,
+		Groups:          groups,
+		OSes:            oses,
+		Arches:          arches,
+		BuildConstraint: buildConstraint,
+		Description:     description,
+	}
+	problems = append(problems, entry.validate()...)
+	return
+}
+
+// validate returns the problems of the entry as messages.
+func (entry *maniEntry) validate() (problems []string) {
+	if err := module.CheckPath(entry.Pkg); err != nil {
+		problems = append(problems, fmt.Sprintf("malformed package path “%s”: %v", entry.Pkg, err))
+	}
+	if entry.Version != latestVer && !semver.IsValid(entry.Version) {
+		problems = append(problems, fmt.Sprintf("invalid version “%s” of %s", entry.Version, entry.Pkg))
+	}
+	for _, require := range entry.Requires {
+		if name, version := splitRequire(require); version != "" && !semver.IsValid(version) {
+			problems = append(problems, fmt.Sprintf("invalid version “%s” of required command “%s”", version, name))
+		}
+	}
+	if entry.BuildConstraint != "" {
+		if _, err := constraint.Parse("//go:build " + entry.BuildConstraint); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid build constraint “%s”: %v", entry.BuildConstraint, err))
+		}
+	}
+	return
+}
+
+// unixOSes are the GOOS values which satisfy the “unix” build constraint.
+var unixOSes = []string{"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "linux", "netbsd", "openbsd", "solaris"}
+
+// impliedOSes maps the GOOS values to the ones which they imply as build tags, as Go does: “android” satisfies “linux”, and “ios” satisfies “darwin”.
+var impliedOSes = map[string]string{"android": "linux", "ios": "darwin"}
+
+// matchOS returns true if the OS name is the GOOS or is implied by it.
+func matchOS(name string, goos string) bool {
+	return name == goos || impliedOSes[goos] == name
+}
+
+// isApplicable returns true if the entry is applicable to the platform.
+func (entry *maniEntry) isApplicable(goos string, goarch string) bool {
+	if len(entry.OSes) > 0 && !slices.ContainsFunc(entry.OSes, func(name string) bool { return matchOS(name, goos) }) {
+		return false
+	}
+	if len(entry.Arches) > 0 && !slices.Contains(entry.Arches, goarch) {
+		return false
+	}
+	if entry.BuildConstraint == "" {
+		return true
+	}
+	expr, err := constraint.Parse("//go:build " + entry.BuildConstraint)
+	if err != nil {
+		return false
+	}
+	return expr.Eval(func(tag string) bool {
+		return matchOS(tag, goos) || tag == goarch || (tag == "unix" && slices.Contains(unixOSes, goos))
+	})
+}
+
+// inGroups returns true if the entry belongs to any of the groups or no group is specified.
+func (entry *maniEntry) inGroups(groups []string) bool {
+	if len(groups) == 0 {
+		return true
+	}
+	return lo.Some(entry.Groups, groups)
+}
+
+// reSectionHeader matches the group section header “[name]”.
+var reSectionHeader = sync.OnceValue(func() *regexp.Regexp { return regexp.MustCompile(`^\[\s*([^\]\s]*)\s*\]$`) })
+
+// mergeEntries merges the entries into the destination. An entry overrides the existing one of the same package.
+func mergeEntries(dst []*maniEntry, entries ...*maniEntry) []*maniEntry {
+outer:
+	for _, entry := range entries {
+		for i, existing := range dst {
+			if existing.Pkg == entry.Pkg {
+				dst[i] = entry
+				continue outer
+			}
+		}
+		dst = append(dst, entry)
+	}
+	return dst
+}
+
+// maniFileT is a manifest file whose includes are not resolved yet.
+type maniFileT struct {
+	path    string
+	inherit bool
+	// includes are the paths of the included manifest files as written, which are relative to the including file.
+	includes []string
+	entries  []*maniEntry
+}
+
+// loadManifestFile loads the manifest file of the line format or of the TOML format according to the file name.
+func loadManifestFile(filePath string) (maniFile *maniFileT, err error) {
+	if filepath.Ext(filePath) == tomlExt {
+		return loadTOMLManifestFile(filePath)
+	}
+	return loadLineManifestFile(filePath)
+}
+
+// loadLineManifestFile loads the manifest file of the line format. All the problems found in the file are reported with their line numbers.
+func loadLineManifestFile(filePath string) (maniFile *maniFileT, err error) {
+	reader, err := os.Open(filePath)
+	if err != nil {
+		return
+	}
+	defer (func() { Ignore(reader.Close()) })()
+	maniFile = &maniFileT{path: filePath}
+	var errs []error
+	// The group of the current section. The entries before any section header belong to no group.
+	sectionGroup := ""
+	scanner := bufio.NewScanner(reader)
+	// The lines of the doc comment, which start with “#:”, for the following entry.
+	var docLines []string
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, docCommentPrefix) {
+			docLines = append(docLines, strings.TrimSpace(strings.TrimPrefix(line, docCommentPrefix)))
+			continue
+		}
+		doc := strings.Join(docLines, " ")
+		docLines = nil
+		fields, err_ := splitManifestLine(line)
+		if err_ != nil {
+			errs = append(errs, &manifestError{filePath, lineNo, err_.Error()})
+			continue
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if matches := reSectionHeader().FindStringSubmatch(strings.Join(fields, " ")); matches != nil {
+			sectionGroup = matches[1]
+			continue
+		}
+		switch fields[0] {
+		case includeDirective:
+			if len(fields) != 2 {
+				errs = append(errs, &manifestError{filePath, lineNo, fmt.Sprintf("“%s” requires a path", includeDirective)})
 				continue
 			}
-			if strings.HasPrefix(line, "#") {
+			includedPath, err_ := unquoteValue(fields[1])
+			if err_ != nil {
+				errs = append(errs, &manifestError{filePath, lineNo, fmt.Sprintf("malformed quoted path of “%s”", includeDirective)})
 				continue
 			}
-			divs := strings.SplitN(line, "#", 2)
-			line = strings.TrimSpace(divs[0])
-			divs = reSpaces().Split(line, 2)
-			pkgVer := divs[0]
-			optsStr := TernaryF(len(divs) >= 2,
-				func() string { return divs[1] },
-				func() string { return "" },
-			)
-			var requires []string
-			var tags string
-			if optsStr != "" {
-				divs = reSpaces().Split(optsStr, -1)
-				for _, opt := range divs {
-					x := strings.SplitN(opt, "=", 2)
-					if len(x) < 2 {
-						continue
-					}
-					key := x[0]
-					val := x[1]
-					switch key {
-					case "requires":
-						reqs := strings.Split(val, ",")
-						for _, req := range reqs {
-							requires = append(requires, req)
-						}
-					case "tags":
-						tags = val
-					}
-				}
+			maniFile.includes = append(maniFile.includes, includedPath)
+			continue
+		case inheritDirective:
+			maniFile.inherit = true
+			continue
+		}
+		entry, problems := parseManifestFields(fields)
+		for _, problem := range problems {
+			errs = append(errs, &manifestError{filePath, lineNo, problem})
+		}
+		entry.Description = Elvis(entry.Description, doc)
+		entry.Source = filePath
+		if sectionGroup != "" && !slices.Contains(entry.Groups, sectionGroup) {
+			entry.Groups = append(entry.Groups, sectionGroup)
+		}
+		maniFile.entries = append(maniFile.entries, entry)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err = errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// quoteValue returns the value of an option quoted if it has the characters which would split or end the field.
+func quoteValue(val string) string {
+	if strings.ContainsAny(val, " \t#\"") {
+		return strconv.Quote(val)
+	}
+	return val
+}
+
+// line returns the line of the manifest file of the line format which defines the entry. The second return value lists the attributes which the line format cannot represent.
+func (entry *maniEntry) line() (line string, lost []string) {
+	line = entry.Pkg + "@" + entry.Version
+	if entry.Tags != "" {
+		line += " tags=" + quoteValue(entry.Tags)
+	}
+	if len(entry.Requires) > 0 {
+		line += " requires=" + quoteValue(strings.Join(entry.Requires, ","))
+	}
+	if len(entry.Groups) > 0 {
+		line += " groups=" + quoteValue(strings.Join(entry.Groups, ","))
+	}
+	if len(entry.OSes) > 0 {
+		line += " os=" + quoteValue(strings.Join(entry.OSes, ","))
+	}
+	if len(entry.Arches) > 0 {
+		line += " arch=" + quoteValue(strings.Join(entry.Arches, ","))
+	}
+	if entry.BuildConstraint != "" {
+		line += " build=" + quoteValue(entry.BuildConstraint)
+	}
+	if entry.Description != "" {
+		line += " desc=" + strconv.Quote(entry.Description)
+	}
+	if len(entry.Env) > 0 {
+		lost = append(lost, "env")
+	}
+	if entry.Ldflags != "" {
+		lost = append(lost, "ldflags")
+	}
+	return
+}
+
+// saveLineManifestFile saves the manifest file in the line format. The attributes which the format cannot represent are dropped with warnings.
+func saveLineManifestFile(maniFile *maniFileT, filePath string, params *installParams) (err error) {
+	defer Catch(&err)
+	var lines []string
+	if maniFile.inherit {
+		lines = append(lines, inheritDirective)
+	}
+	for _, includedPath := range maniFile.includes {
+		lines = append(lines, includeDirective+" "+quoteValue(includedPath))
+	}
+	for _, entry := range maniFile.entries {
+		line, lost := entry.line()
+		if len(lost) > 0 {
+			params.logger().Printf("Dropping %s of %s which cannot be written in %s\n", strings.Join(lost, ", "), entry.Pkg, maniBase)
+		}
+		lines = append(lines, line)
+	}
+	return os.WriteFile(filePath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// appendLineManifestEntries appends the lines of the entries to the manifest file of the line format, creating the file if it does not exist. The existing lines are kept as is, and the entries are put out of the group section, if any, at the end of the file.
+func appendLineManifestEntries(filePath string, entries []*maniEntry, params *installParams) (err error) {
+	defer Catch(&err)
+	content, err_ := os.ReadFile(filePath)
+	if err_ != nil && !errors.Is(err_, os.ErrNotExist) {
+		return err_
+	}
+	var lines []string
+	if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+		lines = append(lines, "")
+	}
+	sectionGroup := ""
+	for _, line := range strings.Split(string(content), "\n") {
+		if matches := reSectionHeader().FindStringSubmatch(strings.TrimSpace(line)); matches != nil {
+			sectionGroup = matches[1]
+		}
+	}
+	if sectionGroup != "" {
+		// The empty section header ends the group section.
+		lines = append(lines, "[]")
+	}
+	for _, entry := range entries {
+		line, lost := entry.line()
+		if len(lost) > 0 {
+			params.logger().Printf("Dropping %s of %s which cannot be written in %s\n", strings.Join(lost, ", "), entry.Pkg, maniBase)
+		}
+		lines = append(lines, line)
+	}
+	file := V(os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644))
+	defer (func() { Ignore(file.Close()) })()
+	V0(file.WriteString(strings.Join(lines, "\n") + "\n"))
+	return
+}
+
+// resolveIncludePath returns the path of the included file. A relative path is relative to the including file.
+func resolveIncludePath(includingPath string, includedPath string) string {
+	if filepath.IsAbs(includedPath) {
+		return includedPath
+	}
+	return filepath.Join(filepath.Dir(includingPath), includedPath)
+}
+
+// readManifestFile reads the manifest file and the files it includes. The returned entries are in the order of precedence, the later ones override the earlier ones. The “inherit” return value reports whether the file has the “inherit” directive.
+func readManifestFile(filePath string, visited map[string]bool) (entries []*maniEntry, inherit bool, err error) {
+	filePath, err = filepath.Abs(filePath)
+	if err != nil {
+		return
+	}
+	if visited[filePath] {
+		return nil, false, fmt.Errorf("manifest file “%s” is included recursively", filePath)
+	}
+	visited[filePath] = true
+	defer delete(visited, filePath)
+	maniFile, err := loadManifestFile(filePath)
+	if err != nil {
+		return
+	}
+	for _, includedPath := range maniFile.includes {
+		includedEntries, _, err_ := readManifestFile(resolveIncludePath(filePath, includedPath), visited)
+		if err_ != nil {
+			return nil, false, err_
+		}
+		entries = mergeEntries(entries, includedEntries...)
+	}
+	entries = mergeEntries(entries, maniFile.entries...)
+	return entries, maniFile.inherit, nil
+}
+
+// manifestFilePath returns the path of the manifest file in the directory, which is either of the line format or of the TOML format. It returns an empty string if no manifest file exists.
+func manifestFilePath(dirPath string) (filePath string, err error) {
+	for _, base := range []string{maniBase, maniTOMLBase} {
+		if stat, err_ := os.Stat(filepath.Join(dirPath, base)); err_ == nil && !stat.IsDir() {
+			if filePath != "" {
+				return "", fmt.Errorf("both %s and %s exist in %s", maniBase, maniTOMLBase, dirPath)
 			}
-			divs = strings.SplitN(pkgVer, "@", 2)
-			pkg := divs[0]
-			ver := TernaryF(len(divs) >= 2,
-				func() string { return divs[1] },
-				func() string { return latestVer },
-			)
-			gobinManifest.entries = append(gobinManifest.entries, &maniEntry{
-				Pkg:      pkg,
-				Version:  ver,
-				Tags:     tags,
-				Requires: requires,
-			})
+			filePath = filepath.Join(dirPath, base)
 		}
 	}
+	return
+}
+
+// parentManifestPath returns the path of the manifest file in the nearest ancestor directory of the given directory. It returns an empty string if no manifest file is found.
+func parentManifestPath(dirPath string) (filePath string, err error) {
+	for {
+		parentDirPath := filepath.Dir(dirPath)
+		if parentDirPath == dirPath {
+			return
+		}
+		dirPath = parentDirPath
+		filePath, err = manifestFilePath(dirPath)
+		if err != nil || filePath != "" {
+			return
+		}
+	}
+}
+
+// readLayeredManifest reads the manifest file and, if it has the “inherit” directive, the manifest files in the ancestor directories. The entries of a manifest file override the ones of its ancestors.
+func readLayeredManifest(filePath string) (entries []*maniEntry, err error) {
+	entries, inherit, err := readManifestFile(filePath, map[string]bool{})
+	if err != nil || !inherit {
+		return
+	}
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return
+	}
+	parentPath, err := parentManifestPath(filepath.Dir(absPath))
+	if err != nil || parentPath == "" {
+		return
+	}
+	parentEntries, err := readLayeredManifest(parentPath)
+	if err != nil {
+		return
+	}
+	entries = mergeEntries(parentEntries, entries...)
+	return
+}
+
+// parseManifest parses the manifest file and the lock file in the directory. The entries are evaluated against the running platform unless overridden by withPlatform.
+func parseManifest(dirPath string, opts ...manifestOption) (gobinManifest *manifestT, err error) {
+	defer Catch(&err)
+	gobinManifest = &manifestT{
+		filePath: Elvis(V(manifestFilePath(dirPath)), filepath.Join(dirPath, maniBase)),
+		lockPath: filepath.Join(dirPath, maniLockBase),
+		goos:     runtime.GOOS,
+		goarch:   runtime.GOARCH,
+	}
+	for _, opt := range opts {
+		opt(gobinManifest)
+	}
+	if _, err_ := os.Stat(gobinManifest.filePath); err_ == nil {
+		gobinManifest.entries = V(readLayeredManifest(gobinManifest.filePath))
+	}
 	if _, err_ := os.Stat(gobinManifest.lockPath); err_ == nil {
 		gobinManifest.pkgMapVer = V(minlib.PkgVerLockMap(dirPath))
 	}
+	// Lock files next to the inherited or included manifest files, used when the nearest lock file has no entry.
+	sourceLockMaps := map[string]minlib.PkgVerLockMapT{}
 	for _, entry := range gobinManifest.entries {
 		if entry.Version != latestVer {
 			entry.LockedVersion = entry.Version
-		} else if lockedVer, ok := gobinManifest.pkgMapVer[entry.Pkg]; ok {
+			continue
+		}
+		if lockedVer, ok := gobinManifest.pkgMapVer[entry.Pkg]; ok {
+			entry.LockedVersion = lockedVer
+			continue
+		}
+		entry.LockedVersion = latestVer
+		sourceDirPath := filepath.Dir(entry.Source)
+		if sourceDirPath == V(filepath.Abs(dirPath)) {
+			continue
+		}
+		lockMap, ok := sourceLockMaps[sourceDirPath]
+		if !ok {
+			lockMap = V(minlib.PkgVerLockMap(sourceDirPath))
+			sourceLockMaps[sourceDirPath] = lockMap
+		}
+		if lockedVer, ok := lockMap[entry.Pkg]; ok {
 			entry.LockedVersion = lockedVer
-		} else {
-			entry.LockedVersion = latestVer
 		}
 	}
 	for pkg, locakedVer := range gobinManifest.pkgMapVer {
@@ -116,9 +613,13 @@ func parseManifest(dirPath string) (gobinManifest *manifestT, err error) {
 				Pkg:           pkg,
 				Version:       latestVer,
 				LockedVersion: locakedVer,
+				Source:        gobinManifest.lockPath,
 			})
 		}
 	}
+	for _, entry := range gobinManifest.entries {
+		entry.Applicable = entry.isApplicable(gobinManifest.goos, gobinManifest.goarch)
+	}
 	return
 }
 
@@ -176,6 +677,25 @@ func (mani *manifestT) lookup(pattern string) (entry *maniEntry) {
 	return
 }
 
+// find returns the entry which matches the pattern as lookup does. It fails with ErrToolNotDefined if no entry matches, and with ErrAmbiguousTool if the pattern is a base name shared by several packages.
+func (mani *manifestT) find(pattern string) (entry *maniEntry, err error) {
+	if !strings.Contains(pattern, "/") && !strings.Contains(pattern, "@") {
+		var pkgs []string
+		for _, entry_ := range mani.entries {
+			if path.Base(entry_.Pkg) == pattern {
+				pkgs = append(pkgs, entry_.Pkg)
+			}
+		}
+		if len(pkgs) > 1 {
+			return nil, newKindError(ErrAmbiguousTool, "command “%s” is ambiguous among %s", pattern, strings.Join(pkgs, ", "))
+		}
+	}
+	if entry = mani.lookup(pattern); entry == nil {
+		return nil, newKindError(ErrToolNotDefined, "command “%s” is not defined", pattern)
+	}
+	return
+}
+
 func (mani *manifestT) Entries() []*maniEntry {
 	return mani.entries
 }
@@ -0,0 +1,142 @@
+package gobin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	. "github.com/knaka/go-utils"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// tomlManifest is the structure of the manifest file of the TOML format, “Gobinfile.toml”.
+type tomlManifest struct {
+	Inherit bool                 `toml:"inherit,omitempty"`
+	Include []string             `toml:"include,omitempty"`
+	Tools   map[string]*tomlTool `toml:"tools,omitempty"`
+}
+
+// tomlTool is the table of a tool in the manifest file of the TOML format.
+type tomlTool struct {
+	Pkg         string   `toml:"pkg"`
+	Version     string   `toml:"version,omitempty"`
+	Description string   `toml:"description,omitempty"`
+	Tags        []string `toml:"tags,omitempty"`
+	Env         []string `toml:"env,omitempty"`
+	Ldflags     string   `toml:"ldflags,omitempty"`
+	Requires    []string `toml:"requires,omitempty"`
+	Groups      []string `toml:"groups,omitempty"`
+	OS          []string `toml:"os,omitempty"`
+	Arch        []string `toml:"arch,omitempty"`
+	Build       string   `toml:"build,omitempty"`
+}
+
+// loadTOMLManifestFile loads the manifest file of the TOML format. The entries are in the order of the tables in the file.
+func loadTOMLManifestFile(filePath string) (maniFile *maniFileT, err error) {
+	var tomlMani tomlManifest
+	metaData, err := toml.DecodeFile(filePath, &tomlMani)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	var errs []error
+	for _, key := range metaData.Undecoded() {
+		errs = append(errs, &manifestError{filePath, 0, fmt.Sprintf("unknown key “%s”", key)})
+	}
+	maniFile = &maniFileT{
+		path:     filePath,
+		inherit:  tomlMani.Inherit,
+		includes: tomlMani.Include,
+	}
+	var names []string
+	for _, key := range metaData.Keys() {
+		if len(key) == 2 && key[0] == "tools" {
+			names = append(names, key[1])
+		}
+	}
+	for _, name := range names {
+		tool := tomlMani.Tools[name]
+		if tool.Pkg == "" {
+			errs = append(errs, &manifestError{filePath, 0, fmt.Sprintf("tools.%s: no “pkg”", name)})
+			continue
+		}
+		entry := &maniEntry{
+			Pkg:             tool.Pkg,
+			Version:         Elvis(tool.Version, latestVer),
+			Tags:            strings.Join(tool.Tags, ","),
+			Requires:        tool.Requires,
+			Groups:          tool.Groups,
+			OSes:            tool.OS,
+			Arches:          tool.Arch,
+			BuildConstraint: tool.Build,
+			Env:             tool.Env,
+			Ldflags:         tool.Ldflags,
+			Description:     tool.Description,
+			Source:          filePath,
+		}
+		for _, problem := range entry.validate() {
+			errs = append(errs, &manifestError{filePath, 0, fmt.Sprintf("tools.%s: %s", name, problem)})
+		}
+		maniFile.entries = append(maniFile.entries, entry)
+	}
+	if err = errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// reBareKey matches the key which can be written without quotes in TOML.
+var reBareKey = sync.OnceValue(func() *regexp.Regexp { return regexp.MustCompile(`^[A-Za-z0-9_-]+$`) })
+
+// tomlKey returns the key quoted if needed.
+func tomlKey(key string) string {
+	if reBareKey().MatchString(key) {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+// saveTOMLManifestFile saves the manifest file in the TOML format. The name of each table is the base name of the package.
+func saveTOMLManifestFile(maniFile *maniFileT, filePath string) (err error) {
+	defer Catch(&err)
+	buf := &bytes.Buffer{}
+	V0(toml.NewEncoder(buf).Encode(&tomlManifest{
+		Inherit: maniFile.inherit,
+		Include: maniFile.includes,
+	}))
+	var names []string
+	for _, entry := range maniFile.entries {
+		name := path.Base(entry.Pkg)
+		for i := 2; slices.Contains(names, name); i++ {
+			name = fmt.Sprintf("%s-%d", path.Base(entry.Pkg), i)
+		}
+		names = append(names, name)
+		tool := &tomlTool{
+			Pkg:         entry.Pkg,
+			Version:     entry.Version,
+			Description: entry.Description,
+			Env:         entry.Env,
+			Ldflags:     entry.Ldflags,
+			Requires:    entry.Requires,
+			Groups:      entry.Groups,
+			OS:          entry.OSes,
+			Arch:        entry.Arches,
+			Build:       entry.BuildConstraint,
+		}
+		if entry.Tags != "" {
+			tool.Tags = strings.Split(entry.Tags, ",")
+		}
+		if buf.Len() > 0 {
+			V0(buf.WriteString("\n"))
+		}
+		// Tables are written one by one to keep the order of the entries.
+		V0(fmt.Fprintf(buf, "[tools.%s]\n", tomlKey(name)))
+		V0(toml.NewEncoder(buf).Encode(tool))
+	}
+	return os.WriteFile(filePath, buf.Bytes(), 0644)
+}
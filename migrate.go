@@ -0,0 +1,184 @@
+package gobin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	. "github.com/knaka/go-utils"
+)
+
+// Manifest file formats.
+const (
+	// ManifestFormatLine is the format of “Gobinfile” which has a package and its options per line.
+	ManifestFormatLine = "line"
+	// ManifestFormatTOML is the format of “Gobinfile.toml” which has a table per tool.
+	ManifestFormatTOML = "toml"
+	// ManifestFormatToolsGo is not a manifest format but the “tools.go” files from which MigrateToolsGo migrates.
+	ManifestFormatToolsGo = "tools-go"
+)
+
+// MigrateManifest converts the manifest file to the given format. The converted file replaces the original one. The lock file is shared by both formats and is kept as is.
+func MigrateManifest(format string, opts ...Option) (err error) {
+	defer Catch(&err)
+	params := newInstallParams()
+	for _, opt := range opts {
+		V0(opt(params))
+	}
+	confDirPath, _ := V2(params.dirs())
+	srcPath := V(manifestFilePath(confDirPath))
+	if srcPath == "" {
+		return errors.New(fmt.Sprintf("no manifest file found in %s", confDirPath))
+	}
+	var dstPath string
+	var save func(*maniFileT, string) error
+	switch format {
+	case ManifestFormatLine:
+		dstPath = filepath.Join(confDirPath, maniBase)
+		save = func(maniFile *maniFileT, filePath string) error {
+			return saveLineManifestFile(maniFile, filePath, params)
+		}
+	case ManifestFormatTOML:
+		dstPath, save = filepath.Join(confDirPath, maniTOMLBase), saveTOMLManifestFile
+	default:
+		return errors.New(fmt.Sprintf("unknown manifest format “%s”", format))
+	}
+	if srcPath == dstPath {
+		params.logger().Printf("%s is already in the %s format\n", srcPath, format)
+		return
+	}
+	maniFile := V(loadManifestFile(srcPath))
+	V0(save(maniFile, dstPath))
+	// The original file is kept unless the converted one can be read back.
+	if _, err_ := loadManifestFile(dstPath); err_ != nil {
+		Ignore(os.Remove(dstPath))
+		return err_
+	}
+	V0(os.Remove(srcPath))
+	params.logger().Printf("Migrated %s to %s\n", srcPath, dstPath)
+	return
+}
+
+// CleanUpToolsGo makes MigrateToolsGo remove the migrated imports from the “tools.go” files, and the files left with no imports, then run “go mod tidy”.
+//
+//goland:noinspection GoUnusedExportedFunction
+func CleanUpToolsGo(f bool) Option {
+	return func(params *installParams) (err error) {
+		params.cleanUpToolsGo = f
+		return
+	}
+}
+
+// MigrateToolsGo migrates the tools imported by the “tools.go” files, which have the “tools” build constraint, to the manifest. The entries are added to the manifest file as “@latest” and are locked to the versions required in go.mod. It returns the migrated packages.
+func MigrateToolsGo(opts ...Option) (pkgs []string, err error) {
+	defer Catch(&err)
+	params := newInstallParams()
+	for _, opt := range opts {
+		V0(opt(params))
+	}
+	confDirPath, _ := V2(params.dirs())
+	return migrateToolsGo(params, confDirPath)
+}
+
+// mainPkgs returns the packages which are main packages, asking the go command in the directory. The others, e.g. the libraries imported for their side effects, are skipped with warnings. The packages which the go command cannot resolve are kept.
+func mainPkgs(pkgs []string, params *installParams, dirPath string) (mains []string, err error) {
+	defer Catch(&err)
+	cmd := exec.CommandContext(params.ctx, params.goCmd(), append([]string{"list", "-e", "-f", "{{.ImportPath}} {{.Name}}"}, pkgs...)...)
+	cmd.Dir = dirPath
+	cmd.Env = append(os.Environ(), params.goEnv...)
+	cmd.Stderr = params.stderr
+	names := map[string]string{}
+	for _, line := range strings.Split(string(V(cmd.Output())), "\n") {
+		if pkg, name, ok := strings.Cut(strings.TrimSpace(line), " "); ok {
+			names[pkg] = name
+		}
+	}
+	for _, pkg := range pkgs {
+		if name := names[pkg]; name != "" && name != "main" {
+			params.logger().Printf("Skipping %s which is not a main package\n", pkg)
+			continue
+		}
+		mains = append(mains, pkg)
+	}
+	return
+}
+
+func migrateToolsGo(params *installParams, confDirPath string) (pkgs []string, err error) {
+	defer Catch(&err)
+	goModDef := V(parseGoMod(confDirPath))
+	if goModDef == nil {
+		return nil, errors.New(fmt.Sprintf("no go.mod found in %s", confDirPath))
+	}
+	files := V(toolsGoFiles(confDirPath))
+	for _, file := range files {
+		for _, pkg := range file.imports {
+			if !slices.Contains(pkgs, pkg) {
+				pkgs = append(pkgs, pkg)
+			}
+		}
+	}
+	if len(pkgs) > 0 {
+		pkgs = V(mainPkgs(pkgs, params, confDirPath))
+	}
+	if len(pkgs) == 0 {
+		return nil, errors.New(fmt.Sprintf("no tools imported with the “%s” build constraint found in %s", toolsBuildTag, confDirPath))
+	}
+	maniPath := V(manifestFilePath(confDirPath))
+	var maniFile *maniFileT
+	if maniPath == "" {
+		maniPath = filepath.Join(confDirPath, maniBase)
+		maniFile = &maniFileT{path: maniPath}
+	} else {
+		maniFile = V(loadManifestFile(maniPath))
+	}
+	var added []*maniEntry
+	for _, pkg := range pkgs {
+		if !slices.ContainsFunc(maniFile.entries, func(entry *maniEntry) bool { return entry.Pkg == pkg }) {
+			added = append(added, &maniEntry{Pkg: pkg, Version: latestVer})
+		}
+	}
+	if len(added) > 0 {
+		if filepath.Ext(maniPath) == tomlExt {
+			maniFile.entries = append(maniFile.entries, added...)
+			V0(saveTOMLManifestFile(maniFile, maniPath))
+		} else {
+			// The comments, the section headers and the layout of the existing file are kept.
+			V0(appendLineManifestEntries(maniPath, added, params))
+		}
+	}
+	params.logger().Printf("Added %d tool(s) to %s\n", len(added), maniPath)
+	// Lock the entries to the versions in go.mod unless they are locked already.
+	manifest := V(parseManifest(confDirPath))
+	for _, pkg := range pkgs {
+		entry := manifest.lookup(pkg)
+		if entry == nil || entry.LockedVersion != latestVer {
+			continue
+		}
+		if reqMod := goModDef.requiredModuleByPkg(pkg); reqMod != nil {
+			entry.LockedVersion = reqMod.Version
+		} else {
+			params.logger().Printf("%s is not required in go.mod and is locked on install\n", pkg)
+		}
+	}
+	V0(manifest.saveLockfile())
+	if !params.cleanUpToolsGo {
+		return
+	}
+	for _, file := range files {
+		if V(file.removeImports(pkgs)) {
+			params.logger().Printf("Removed %s\n", file.path)
+		} else {
+			params.logger().Printf("Removed the imports of the tools from %s\n", file.path)
+		}
+	}
+	cmd := exec.CommandContext(params.ctx, params.goCmd(), "mod", "tidy")
+	cmd.Dir = confDirPath
+	cmd.Stdout = params.stdout
+	cmd.Stderr = params.stderr
+	V0(cmd.Run())
+	return
+}
@@ -2,22 +2,24 @@ package minlib
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"crypto/sha1"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 )
 
-var verbose = false
-
 func v0(err error) {
 	if err != nil {
 		panic(err)
@@ -38,9 +40,42 @@ func v2[T any, U any](t T, u U, err error) (T, U) {
 	return t, u
 }
 
+// catch recovers the panic of v0, v and v2 into the error, so that the exported functions return errors instead of panicking.
+func catch(errRef *error) {
+	if r := recover(); r != nil {
+		if err, ok := r.(error); ok {
+			*errRef = err
+			return
+		}
+		*errRef = fmt.Errorf("%v", r)
+	}
+}
+
+// ErrNoConfig is the error of ConfDirPath when neither the manifest file nor go.mod is found in the directory and its ancestors.
+var ErrNoConfig = errors.New("no go.mod or manifest file found")
+
+// InstallError is the error of the build of the package by the go command.
+type InstallError struct {
+	Pkg     string
+	Version string
+	// Output is the output of the go command.
+	Output string
+	// Err is the error of the go command, which wraps the error of the context if it is done.
+	Err error
+}
+
+func (e *InstallError) Error() string {
+	return fmt.Sprintf("failed to install %s@%s: %v", e.Pkg, e.Version, e.Err)
+}
+
+func (e *InstallError) Unwrap() error {
+	return e.Err
+}
+
 type paramsT struct {
 	initialDirPath string
 	global         bool
+	rootDirPath    string
 }
 
 type ConfDirPathOption func(*paramsT) error
@@ -73,30 +108,29 @@ func WithGlobal(f bool) ConfDirPathOption {
 	}
 }
 
-var mockRootDirPath = ""
-
-// setMockRootDir sets the root directory path for testing.
-// Introduced because the temporary directory of Windows is under the user home directory.
-func setMockRootDir(dir string) {
-	mockRootDirPath = v(realpath(dir))
+// WithRootDir stops looking up the configuration directory at the directory instead of the root of the file system.
+// Introduced for testing because the temporary directory of Windows is under the user home directory.
+func WithRootDir(dir string) ConfDirPathOption {
+	return func(params *paramsT) (err error) {
+		params.rootDirPath, err = realpath(dir)
+		return
+	}
 }
 
-func isRootDir(dir string) bool {
+func isRootDir(dir string, rootDirPath string) bool {
 	dirPath, err := realpath(dir)
 	if err != nil {
 		return false
 	}
-	if mockRootDirPath != "" {
-		return dirPath == mockRootDirPath
-	}
 	dirPath = filepath.Clean(dirPath)
-	return dirPath == filepath.Dir(dirPath)
+	return dirPath == rootDirPath || dirPath == filepath.Dir(dirPath)
 }
 
 type PkgVerLockMapT map[string]string
 
 const GobinCmdBase = "gobin"
 const ManifestFileBase = "Gobinfile"
+const ManifestTOMLFileBase = "Gobinfile.toml"
 const ManifestLockFileBase = "Gobinfile-lock"
 const goModFileBase = "go.mod"
 const GobinDirBase = ".gobin"
@@ -113,12 +147,15 @@ func GlobalConfDirPath() (confDirPath string, gobinPath string, err error) {
 	return
 }
 
-func parentDirOf(dir string) string {
-	dirPath := v(realpath(dir))
-	if mockRootDirPath != "" && mockRootDirPath == dirPath {
-		return dir
+func parentDirOf(dir string, rootDirPath string) (parentDirPath string, err error) {
+	dirPath, err := realpath(dir)
+	if err != nil {
+		return
 	}
-	return filepath.Dir(dirPath)
+	if rootDirPath != "" && rootDirPath == dirPath {
+		return dir, nil
+	}
+	return filepath.Dir(dirPath), nil
 }
 
 // ConfDirPath returns the configuration directory path. If the global option is true, it returns the global (home)  configuration directory path. This returns the directory which contains the manifest file. If no manifest file is found in any parent directory, it returns the directory which contains the go.mod file.
@@ -156,17 +193,23 @@ func ConfDirPath(opts ...ConfDirPathOption) (
 		if stat, err_ := os.Stat(filepath.Join(confDirPath, ManifestFileBase)); err_ == nil && !stat.IsDir() {
 			break
 		}
+		if stat, err_ := os.Stat(filepath.Join(confDirPath, ManifestTOMLFileBase)); err_ == nil && !stat.IsDir() {
+			break
+		}
 		if stat, err_ := os.Stat(filepath.Join(confDirPath, ManifestLockFileBase)); err_ == nil && stat.IsDir() {
 			break
 		}
-		confDirPath = parentDirOf(confDirPath)
-		if isRootDir(confDirPath) {
+		confDirPath, err = parentDirOf(confDirPath, params.rootDirPath)
+		if err != nil {
+			return
+		}
+		if isRootDir(confDirPath, params.rootDirPath) {
 			// If no manifest file is found in any parent directory, return the directory which contains the go.mod file.
 			if goModDirPath != "" {
 				confDirPath = goModDirPath
 				break
 			}
-			confDirPath, gobinPath, err = "", "", errors.New("no go.mod or manifest file found")
+			confDirPath, gobinPath, err = "", "", ErrNoConfig
 			return
 		}
 	}
@@ -181,105 +224,472 @@ type GoListOutput struct {
 
 // PkgVerLockMap returns the package version lock map.
 func PkgVerLockMap(dirPath string) (lockList PkgVerLockMapT, err error) {
+	lockList, _, err = PkgVerLockMapWithLines(dirPath)
+	return
+}
+
+// PkgVerLockMapWithLines is PkgVerLockMap which also returns the line numbers of the packages in the lock file.
+func PkgVerLockMapWithLines(dirPath string) (lockList PkgVerLockMapT, lineNos map[string]int, err error) {
 	manifestLockPath := filepath.Join(dirPath, ManifestLockFileBase)
 	if _, err_ := os.Stat(manifestLockPath); err_ != nil {
 		return
 	}
-	reader := v(os.Open(manifestLockPath))
+	reader, err := os.Open(manifestLockPath)
+	if err != nil {
+		return
+	}
+	defer (func() { _ = reader.Close() })()
 	scanner := bufio.NewScanner(reader)
 	lockList = make(PkgVerLockMapT)
-	for scanner.Scan() {
+	lineNos = map[string]int{}
+	for lineNo := 1; scanner.Scan(); lineNo++ {
 		line := scanner.Text()
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
 		divs := strings.SplitN(line, "@", 2)
+		if len(divs) < 2 || divs[0] == "" || divs[1] == "" {
+			return nil, nil, fmt.Errorf("%s:%d: malformed line “%s”, which should be “package@version”", manifestLockPath, lineNo, line)
+		}
 		lockList[divs[0]] = divs[1]
+		lineNos[divs[0]] = lineNo
 	}
+	err = scanner.Err()
 	return
 }
 
-// EnsureInstalled ensures that the program package is installed.
-func EnsureInstalled(gobinPath string, pkgPath string, ver string, tags string, log *log.Logger, _ *log.Logger) (cmdPkgVerPath string, err error) {
-	pkgBase := path.Base(pkgPath)
-	pkgBaseVer := pkgBase + "@" + ver
-	cmdPath := filepath.Join(gobinPath, pkgBase+exeExt())
-	if tags != "" {
+type installParamsT struct {
+	ldflags      string
+	env          []string
+	shimStrategy ShimStrategy
+	vlogger      *log.Logger
+	goCmdPath    string
+	goEnv        []string
+	ctx          context.Context
+	output       io.Writer
+}
+
+// discardLogger is the logger of the messages which nobody receives.
+var discardLogger = log.New(io.Discard, "", 0)
+
+// newInstallParams returns the parameters with the options applied. The verbose messages are discarded by default.
+func newInstallParams(opts []InstallOption) (params *installParamsT) {
+	params = &installParamsT{
+		vlogger: discardLogger,
+		ctx:     context.Background(),
+		output:  os.Stderr,
+	}
+	for _, opt := range opts {
+		opt(params)
+	}
+	return
+}
+
+// goCmd returns the go command to build the packages, which is the one of the managed Go SDK unless specified.
+func (params *installParamsT) goCmd() (string, error) {
+	if params.goCmdPath != "" {
+		return params.goCmdPath, nil
+	}
+	return goCmdPath(params.ctx, params.output)
+}
+
+// contextErr returns the error of the command wrapped with the error of the context if the context is done, which is the cause of the failure then.
+func contextErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+	return err
+}
+
+type InstallOption func(*installParamsT)
+
+// WithLdflags sets the flags passed to the linker to build the package.
+func WithLdflags(ldflags string) InstallOption {
+	return func(params *installParamsT) {
+		params.ldflags = ldflags
+	}
+}
+
+// WithBuildEnv sets the environment variables in the form of “KEY=VALUE” to build the package.
+func WithBuildEnv(env []string) InstallOption {
+	return func(params *installParamsT) {
+		params.env = env
+	}
+}
+
+// WithShimStrategy sets the way to create the shims of the commands and the link to the gobin command.
+func WithShimStrategy(strategy ShimStrategy) InstallOption {
+	return func(params *installParamsT) {
+		params.shimStrategy = strategy
+	}
+}
+
+// WithVerboseLogger sets the logger of the verbose messages, which are discarded by default.
+func WithVerboseLogger(vlogger *log.Logger) InstallOption {
+	return func(params *installParamsT) {
+		if vlogger != nil {
+			params.vlogger = vlogger
+		}
+	}
+}
+
+// WithGoCmdPath sets the go command to build and query the packages instead of the one of the managed Go SDK.
+func WithGoCmdPath(goCmdPath string) InstallOption {
+	return func(params *installParamsT) {
+		params.goCmdPath = goCmdPath
+	}
+}
+
+// WithGoEnv sets the environment variables in the form of “KEY=VALUE” for the go command, e.g. GOPROXY, which apply to all the packages unlike WithBuildEnv.
+func WithGoEnv(env []string) InstallOption {
+	return func(params *installParamsT) {
+		params.goEnv = env
+	}
+}
+
+// WithContext sets the context which kills the go command and the download of the Go SDK when done.
+func WithContext(ctx context.Context) InstallOption {
+	return func(params *installParamsT) {
+		if ctx != nil {
+			params.ctx = ctx
+		}
+	}
+}
+
+// WithOutput sets the writer of the output of the go command and of the download of the Go SDK, which is the standard error by default.
+func WithOutput(output io.Writer) InstallOption {
+	return func(params *installParamsT) {
+		if output != nil {
+			params.output = output
+		}
+	}
+}
+
+// cmdPkgBaseVer returns the file name of the binary of the package of the version without the executable extension. The binaries built with different flags are distinguished by the hash of the flags.
+func cmdPkgBaseVer(pkgPath string, ver string, tags string, params *installParamsT) string {
+	ret := path.Base(pkgPath) + "@" + ver
+	if tags != "" || params.ldflags != "" || len(params.env) > 0 {
+		// Only tags are hashed as is for compatibility.
+		buildFlags := tags
+		if params.ldflags != "" || len(params.env) > 0 {
+			buildFlags = strings.Join(append([]string{tags, params.ldflags}, params.env...), "\x00")
+		}
 		hash := sha1.New()
-		hash.Write([]byte(tags))
+		hash.Write([]byte(buildFlags))
 		sevenDigits := fmt.Sprintf("%x", hash.Sum(nil))[:7]
-		pkgBaseVer += "-" + sevenDigits
+		ret += "-" + sevenDigits
+	}
+	return ret
+}
+
+// InstalledCmdPath returns the path of the binary to which EnsureInstalled installs the program package.
+func InstalledCmdPath(gobinPath string, pkgPath string, ver string, tags string, opts ...InstallOption) string {
+	params := newInstallParams(opts)
+	return filepath.Join(gobinPath, cmdPkgBaseVer(pkgPath, ver, tags, params)+exeExt())
+}
+
+// EnsureInstalled ensures that the program package is installed. The verbose messages go to vlog, which WithVerboseLogger overrides.
+func EnsureInstalled(gobinPath string, pkgPath string, ver string, tags string, log *log.Logger, vlog *log.Logger, opts ...InstallOption) (cmdPkgVerPath string, err error) {
+	defer catch(&err)
+	params := newInstallParams(append([]InstallOption{WithVerboseLogger(vlog)}, opts...))
+	if log == nil {
+		log = discardLogger
 	}
+	pkgBase := path.Base(pkgPath)
+	pkgBaseVer := cmdPkgBaseVer(pkgPath, ver, tags, params)
+	cmdPath := filepath.Join(gobinPath, pkgBase+exeExt())
 	cmdPkgVerPath = filepath.Join(gobinPath, pkgBaseVer+exeExt())
 	if _, err_ := os.Stat(cmdPkgVerPath); err_ != nil {
-		if verbose {
-			log.Printf("Installing %s@%s\n", pkgPath, ver)
-		}
-		args := []string{"install", fmt.Sprintf("%s@%s", pkgPath, ver)}
+		goCmdPath := v(params.goCmd())
+		params.vlogger.Printf("Installing %s@%s with %s\n", pkgPath, ver, goCmdPath)
+		// Build flags should precede the package.
+		args := []string{"install"}
 		if tags != "" {
 			log.Printf("Installing with tags %s\n", tags)
 			args = append(args, "-tags", tags)
 		}
-		cmd := exec.Command(goCmdPath(), args...)
-		cmd.Env = append(os.Environ(), fmt.Sprintf("GOBIN=%s", gobinPath))
-		cmd.Stdout = os.Stderr
-		cmd.Stderr = os.Stderr
+		if params.ldflags != "" {
+			args = append(args, "-ldflags", params.ldflags)
+		}
+		args = append(args, fmt.Sprintf("%s@%s", pkgPath, ver))
+		cmd := exec.CommandContext(params.ctx, goCmdPath, args...)
+		cmd.Env = append(append(append(os.Environ(), params.goEnv...), params.env...), fmt.Sprintf("GOBIN=%s", gobinPath))
+		// The output is also kept to attach to the error.
+		var output bytes.Buffer
+		cmd.Stdout = io.MultiWriter(params.output, &output)
+		cmd.Stderr = cmd.Stdout
 		_ = os.Remove(cmdPath)
-		v0(cmd.Run())
+		if err_ := cmd.Run(); err_ != nil {
+			return "", &InstallError{Pkg: pkgPath, Version: ver, Output: output.String(), Err: contextErr(params.ctx, err_)}
+		}
 		_ = os.Remove(cmdPkgVerPath)
 		v0(os.Rename(cmdPath, cmdPkgVerPath))
-		if pkgBase == GobinCmdBase {
-			v0(os.Symlink(pkgBaseVer+exeExt(), cmdPath))
-		} else {
-			v0(os.Symlink(GobinCmdBase+exeExt(), cmdPath))
+		if pkgBase != GobinCmdBase {
+			_ = v(EnsureShim(gobinPath, pkgBase, opts...))
 		}
 	}
+	if pkgBase == GobinCmdBase {
+		// The link is ensured even if installed already because the shim strategy can differ from the one at the installation.
+		v0(linkFile(gobinPath, pkgBaseVer+exeExt(), GobinCmdBase+exeExt(), params.shimStrategy))
+	}
 	return
 }
 
-var Goroot = sync.OnceValues(func() (gobinPath string, err error) {
-	// 1.22.7 seems not working on Windows?
-	ver := "1.23.1"
-	homeDir := v(os.UserHomeDir())
+// ShimStrategy is the way to create a shim, which runs the gobin command in place of a command.
+type ShimStrategy string
+
+const (
+	// ShimAuto tries ShimSymlink, ShimHardlink and ShimCopy in order.
+	ShimAuto ShimStrategy = ""
+	// ShimSymlink creates a symbolic link, which needs the developer mode or the administrator rights on Windows.
+	ShimSymlink ShimStrategy = "symlink"
+	// ShimHardlink creates a hard link, which needs no special rights.
+	ShimHardlink ShimStrategy = "hardlink"
+	// ShimCopy copies the gobin command.
+	ShimCopy ShimStrategy = "copy"
+	// ShimCmd creates a “.cmd” wrapper script which runs the gobin command. The gobin command itself is hard-linked or copied.
+	ShimCmd ShimStrategy = "cmd"
+)
+
+// ShimStrategies are the strategies which can be specified explicitly.
+var ShimStrategies = []ShimStrategy{ShimSymlink, ShimHardlink, ShimCopy, ShimCmd}
+
+// isSameFile returns true if the file at the link path is the file at the target path itself, without following the link.
+func isSameFile(targetPath string, linkPath string) bool {
+	linkInfo, err := os.Lstat(linkPath)
+	if err != nil {
+		return false
+	}
+	targetInfo, err := os.Stat(targetPath)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(targetInfo, linkInfo)
+}
+
+// isUpToDateCopy returns true if the file at the copy path is a regular file which has been copied from the target path after its last modification.
+func isUpToDateCopy(targetPath string, copyPath string) bool {
+	copyInfo, err := os.Lstat(copyPath)
+	if err != nil || !copyInfo.Mode().IsRegular() {
+		return false
+	}
+	targetInfo, err := os.Stat(targetPath)
+	if err != nil {
+		return false
+	}
+	return copyInfo.Size() == targetInfo.Size() && !copyInfo.ModTime().Before(targetInfo.ModTime())
+}
+
+func copyFile(srcPath string, dstPath string) (err error) {
+	reader, err := os.Open(srcPath)
+	if err != nil {
+		return
+	}
+	defer (func() { _ = reader.Close() })()
+	writer, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return
+	}
+	_, err = io.Copy(writer, reader)
+	return errors.Join(err, writer.Close())
+}
+
+// fallbackStrategies returns the strategies to try in order for the strategy.
+func fallbackStrategies(strategy ShimStrategy) []ShimStrategy {
+	switch strategy {
+	case ShimAuto:
+		return []ShimStrategy{ShimSymlink, ShimHardlink, ShimCopy}
+	case ShimCmd:
+		// A “.cmd” wrapper cannot stand for the executable which other wrappers run.
+		return []ShimStrategy{ShimHardlink, ShimCopy}
+	}
+	return []ShimStrategy{strategy}
+}
+
+// isLinked returns true if the file of the link base in the gobin directory has been linked to the file of the target base with the strategy.
+func isLinked(gobinPath string, targetBase string, linkBase string, strategy ShimStrategy) bool {
+	linkPath := filepath.Join(gobinPath, linkBase)
+	if strategy == ShimSymlink {
+		linkTarget, err := os.Readlink(linkPath)
+		return err == nil && linkTarget == targetBase
+	}
+	// The target can be a symlink created with ShimSymlink before.
+	targetPath, err := filepath.EvalSymlinks(filepath.Join(gobinPath, targetBase))
+	if err != nil {
+		return false
+	}
+	if strategy == ShimHardlink {
+		return isSameFile(targetPath, linkPath)
+	}
+	return isUpToDateCopy(targetPath, linkPath)
+}
+
+// linkFile makes the file of the link base in the gobin directory run the same program as the file of the target base. The existing link is kept if it has been created with any of the strategies to try.
+func linkFile(gobinPath string, targetBase string, linkBase string, strategy ShimStrategy) (err error) {
+	strategies := fallbackStrategies(strategy)
+	for _, strategy := range strategies {
+		if !slices.Contains(ShimStrategies, strategy) {
+			return fmt.Errorf("unknown shim strategy “%s”", strategy)
+		}
+		if isLinked(gobinPath, targetBase, linkBase, strategy) {
+			return
+		}
+	}
+	targetPath := filepath.Join(gobinPath, targetBase)
+	linkPath := filepath.Join(gobinPath, linkBase)
+	var errs []error
+	for _, strategy := range strategies {
+		_ = os.Remove(linkPath)
+		switch strategy {
+		case ShimSymlink:
+			err = os.Symlink(targetBase, linkPath)
+		case ShimHardlink, ShimCopy:
+			var resolvedPath string
+			if resolvedPath, err = filepath.EvalSymlinks(targetPath); err != nil {
+				break
+			}
+			if strategy == ShimHardlink {
+				err = os.Link(resolvedPath, linkPath)
+			} else {
+				err = copyFile(resolvedPath, linkPath)
+			}
+		}
+		if err == nil {
+			return
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// cmdShimContent returns the content of the “.cmd” wrapper script which runs the command through the gobin command in the same directory.
+func cmdShimContent(gobinPath string, cmdBase string) string {
+	globalOpt := ""
+	if _, globalGobinPath, err := GlobalConfDirPath(); err == nil && filepath.Clean(globalGobinPath) == filepath.Clean(gobinPath) {
+		globalOpt = " -g"
+	}
+	return fmt.Sprintf("@echo off\r\n\"%%~dp0%s.exe\"%s run %s %%*\r\n", GobinCmdBase, globalOpt, cmdBase)
+}
+
+// EnsureShim creates the shim of the command in the gobin directory. The shim runs the gobin command, which installs the command of the appropriate version on first use and runs it.
+func EnsureShim(gobinPath string, cmdBase string, opts ...InstallOption) (shimPath string, err error) {
+	params := newInstallParams(opts)
+	if params.shimStrategy == ShimCmd {
+		shimPath = filepath.Join(gobinPath, cmdBase+".cmd")
+		content := cmdShimContent(gobinPath, cmdBase)
+		if existing, err_ := os.ReadFile(shimPath); err_ == nil && string(existing) == content {
+			return
+		}
+		err = os.WriteFile(shimPath, []byte(content), 0755)
+		return
+	}
+	shimPath = filepath.Join(gobinPath, cmdBase+exeExt())
+	err = linkFile(gobinPath, GobinCmdBase+exeExt(), cmdBase+exeExt(), params.shimStrategy)
+	return
+}
+
+// goSDKVersion is the version of the managed Go SDK.
+// 1.22.7 seems not working on Windows?
+const goSDKVersion = "1.23.1"
+
+var (
+	gorootMutex sync.Mutex
+	gorootPath  string
+)
+
+// Goroot returns the GOROOT of the managed Go SDK, downloading it on first use.
+func Goroot() (string, error) {
+	return GorootContext(context.Background())
+}
+
+// GorootContext is Goroot with the context, which kills the download when done. The download is retried on the next call if it fails. The output of the download goes to the writer of WithOutput.
+func GorootContext(ctx context.Context, opts ...InstallOption) (string, error) {
+	return goroot(ctx, newInstallParams(opts).output)
+}
+
+// goroot returns the GOROOT of the managed Go SDK, writing the output of the download to the writer.
+func goroot(ctx context.Context, output io.Writer) (string, error) {
+	// The done context fails even if the SDK is ready, as the command run with it would.
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	gorootMutex.Lock()
+	defer gorootMutex.Unlock()
+	if gorootPath != "" {
+		return gorootPath, nil
+	}
+	root, err := ensureGoroot(ctx, output)
+	if err != nil {
+		return "", contextErr(ctx, err)
+	}
+	gorootPath = root
+	return gorootPath, nil
+}
+
+func ensureGoroot(ctx context.Context, output io.Writer) (gorootPath string, err error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
 	sdkDirPath := filepath.Join(homeDir, "sdk")
-	gorootPath := filepath.Join(sdkDirPath, "go"+ver)
+	gorootPath = filepath.Join(sdkDirPath, "go"+goSDKVersion)
 	goCmdPath := filepath.Join(gorootPath, "bin", "go"+exeExt())
-	if _, err := os.Stat(goCmdPath); err == nil {
-		return gorootPath, nil
+	if _, err_ := os.Stat(goCmdPath); err_ == nil {
+		return
+	}
+	tempDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		return
 	}
-	tempDir := v(os.MkdirTemp("", ""))
-	defer (func() { v0(os.RemoveAll(tempDir)) })()
+	defer (func() { _ = os.RemoveAll(tempDir) })()
 	arcPath := filepath.Join(tempDir, "temp.tgz")
-	url := fmt.Sprintf("https://go.dev/dl/go%s.%s-%s.tar.gz", ver, runtime.GOOS, runtime.GOARCH)
+	url := fmt.Sprintf("https://go.dev/dl/go%s.%s-%s.tar.gz", goSDKVersion, runtime.GOOS, runtime.GOARCH)
 	//goland:noinspection GoBoolExpressions
 	if runtime.GOOS == "windows" {
 		arcPath = filepath.Join(tempDir, "temp.zip")
-		url = fmt.Sprintf("https://go.dev/dl/go%s.%s-%s.zip", ver, runtime.GOOS, runtime.GOARCH)
+		url = fmt.Sprintf("https://go.dev/dl/go%s.%s-%s.zip", goSDKVersion, runtime.GOOS, runtime.GOARCH)
 	}
-	cmd := exec.Command("curl"+exeExt(), "--location", "-o", arcPath, url)
-	cmd.Stdout = os.Stderr
-	cmd.Stderr = os.Stderr
-	v0(cmd.Run())
-	cmd = exec.Command("tar"+exeExt(), "-C", sdkDirPath, "-xzf", arcPath)
-	cmd.Stdout = os.Stderr
-	cmd.Stderr = os.Stderr
-	v0(cmd.Run())
-	v0(os.Rename(filepath.Join(sdkDirPath, "go"), gorootPath))
-	return gorootPath, nil
-})
+	cmd := exec.CommandContext(ctx, "curl"+exeExt(), "--location", "-o", arcPath, url)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err = cmd.Run(); err != nil {
+		return "", err
+	}
+	// Remove the directory which an interrupted extraction left.
+	if err = os.RemoveAll(filepath.Join(sdkDirPath, "go")); err != nil {
+		return "", err
+	}
+	cmd = exec.CommandContext(ctx, "tar"+exeExt(), "-C", sdkDirPath, "-xzf", arcPath)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err = cmd.Run(); err != nil {
+		return "", err
+	}
+	if err = os.Rename(filepath.Join(sdkDirPath, "go"), gorootPath); err != nil {
+		return "", err
+	}
+	return
+}
+
+var prependGorootPathOnce sync.Once
 
-var goCmdPath = sync.OnceValue(func() string {
-	binDirPath := filepath.Join(v(Goroot()), "bin")
-	v0(os.Setenv("PATH", fmt.Sprintf("%s%c%s", binDirPath, filepath.ListSeparator, os.Getenv("PATH"))))
-	cmdPath := filepath.Join(binDirPath, "go"+exeExt())
-	if verbose {
-		log.Printf("The path to the go command is %s\n", cmdPath)
+// goCmdPath returns the go command of the managed Go SDK, whose bin directory is prepended to PATH for the commands which run “go” by themselves.
+func goCmdPath(ctx context.Context, output io.Writer) (string, error) {
+	root, err := goroot(ctx, output)
+	if err != nil {
+		return "", err
 	}
-	return cmdPath
-})
+	binDirPath := filepath.Join(root, "bin")
+	prependGorootPathOnce.Do(func() {
+		_ = os.Setenv("PATH", fmt.Sprintf("%s%c%s", binDirPath, filepath.ListSeparator, os.Getenv("PATH")))
+	})
+	return filepath.Join(binDirPath, "go"+exeExt()), nil
+}
 
-func EnsureGobinCmdInstalled(global bool) (cmdPath string, err error) {
+func EnsureGobinCmdInstalled(global bool, installOpts ...InstallOption) (cmdPath string, err error) {
+	defer catch(&err)
+	params := newInstallParams(installOpts)
 	var opts []ConfDirPathOption
 	if global {
 		opts = append(opts, WithGlobal(true))
@@ -290,30 +700,25 @@ func EnsureGobinCmdInstalled(global bool) (cmdPath string, err error) {
 	pkgPath := "github.com/knaka/gobin/cmd/gobin"
 	ver, ok := pkgVerLockMap[pkgPath]
 	if ok {
-		if verbose {
-			log.Printf("The locked version of %s is %s\n", pkgPath, ver)
-		}
+		params.vlogger.Printf("The locked version of %s is %s\n", pkgPath, ver)
 	} else {
-		if verbose {
-			log.Printf("Querying the latest version of %s\n", pkgPath)
-		}
-		cmd := exec.Command(goCmdPath(), "list", "-m",
+		params.vlogger.Printf("Querying the latest version of %s\n", pkgPath)
+		cmd := exec.CommandContext(params.ctx, v(params.goCmd()), "list", "-m",
 			"--json", fmt.Sprintf("%s@%s", modPath, "latest"))
-		cmd.Env = append(os.Environ(), "GO111MODULE=on")
-		cmd.Stderr = os.Stderr
-		output := v(cmd.Output())
+		cmd.Env = append(append(os.Environ(), params.goEnv...), "GO111MODULE=on")
+		cmd.Stderr = params.output
+		output, err_ := cmd.Output()
+		v0(contextErr(params.ctx, err_))
 		goListOutput := GoListOutput{}
 		v0(json.Unmarshal(output, &goListOutput))
 		ver = goListOutput.Version
-		if verbose {
-			log.Printf("The latest version of %s is %s\n", pkgPath, ver)
-		}
+		params.vlogger.Printf("The latest version of %s is %s\n", pkgPath, ver)
 		manifestLockPath := filepath.Join(confDirPath, ManifestLockFileBase)
 		writer := v(os.OpenFile(manifestLockPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600))
 		defer (func() { v0(writer.Close()) })()
 		_ = v(writer.WriteString(fmt.Sprintf("%s@%s\n", pkgPath, ver)))
 	}
-	return EnsureInstalled(gobinPath, pkgPath, ver, "", log.Default(), log.Default())
+	return EnsureInstalled(gobinPath, pkgPath, ver, "", log.Default(), params.vlogger, installOpts...)
 }
 
 func Command(name string, arg ...string) (cmd *exec.Cmd, err error) {
@@ -336,6 +741,7 @@ func RunCommand(name string, arg ...string) (execErr *exec.ExitError, err error)
 
 // bootstrapMain is the main function of the bootstrap command.
 func bootstrapMain() {
+	var installOpts []InstallOption
 outer:
 	for {
 		if len(os.Args) <= 1 {
@@ -343,13 +749,13 @@ outer:
 		}
 		switch os.Args[1] {
 		case "--verbose":
-			verbose = true
+			installOpts = append(installOpts, WithVerboseLogger(log.Default()))
 		default:
 			break outer
 		}
 		os.Args = append(os.Args[:1], os.Args[2:]...)
 	}
-	gobinCmdPath := v(EnsureGobinCmdInstalled(false))
+	gobinCmdPath := v(EnsureGobinCmdInstalled(false, installOpts...))
 	errExec, err := RunCommand(gobinCmdPath, os.Args[1:]...)
 	if err == nil {
 		os.Exit(0)
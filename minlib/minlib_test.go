@@ -1,19 +1,21 @@
 package minlib
 
 import (
+	"bytes"
+	"context"
 	fsutils "github.com/knaka/go-utils/fs"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"testing"
+	"time"
 
 	. "github.com/knaka/go-utils"
 )
 
 func TestGetConfPath(t *testing.T) {
 	defaultTempDir := os.TempDir()
-	setMockRootDir(defaultTempDir)
 	Ignore(os.Remove(filepath.Join(defaultTempDir, "go.mod")))
 	tempDir := V(realpath(V(os.MkdirTemp(defaultTempDir, "gobin-test"))))
 	noGoMod := filepath.Join(tempDir, "no-go-mod")
@@ -94,7 +96,7 @@ func TestGetConfPath(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotConfDirPath, gotGobinDirPath, err := ConfDirPath(tt.args.opts...)
+			gotConfDirPath, gotGobinDirPath, err := ConfDirPath(append(tt.args.opts, WithRootDir(defaultTempDir))...)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ConfDirPath() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -152,3 +154,98 @@ func Test_getGobin(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, "", gobin)
 }
+
+func Test_manifestLockModulesMalformed(t *testing.T) {
+	tempDir := V(realpath(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	V0(os.WriteFile(filepath.Join(tempDir, ManifestLockFileBase), []byte("golang.org/x/tools/cmd/stringer@v0.23.0\ngolang.org/x/tools/cmd/goyacc\n"), 0644))
+	_, err := PkgVerLockMap(tempDir)
+	assert.ErrorContains(t, err, ManifestLockFileBase+":2: malformed line")
+}
+
+func TestEnsureShim(t *testing.T) {
+	for _, strategy := range ShimStrategies {
+		t.Run(string(strategy), func(t *testing.T) {
+			gobinPath := V(realpath(V(os.MkdirTemp("", "gobin-test"))))
+			t.Cleanup(func() { Ignore(os.RemoveAll(gobinPath)) })
+			gobinVerBase := GobinCmdBase + "@v0.1.0" + exeExt()
+			V0(os.WriteFile(filepath.Join(gobinPath, gobinVerBase), []byte("gobin binary"), 0755))
+			V0(linkFile(gobinPath, gobinVerBase, GobinCmdBase+exeExt(), strategy))
+			shimPath := V(EnsureShim(gobinPath, "stringer", WithShimStrategy(strategy)))
+			// Ensuring again keeps the shim.
+			assert.Equal(t, shimPath, V(EnsureShim(gobinPath, "stringer", WithShimStrategy(strategy))))
+			gobinCmdPath := filepath.Join(gobinPath, GobinCmdBase+exeExt())
+			switch strategy {
+			case ShimSymlink:
+				assert.Equal(t, GobinCmdBase+exeExt(), V(os.Readlink(shimPath)))
+			case ShimHardlink:
+				assert.True(t, isSameFile(gobinCmdPath, shimPath))
+			case ShimCopy:
+				assert.False(t, isSameFile(gobinCmdPath, shimPath))
+				assert.Equal(t, "gobin binary", string(V(os.ReadFile(shimPath))))
+			case ShimCmd:
+				assert.Equal(t, filepath.Join(gobinPath, "stringer.cmd"), shimPath)
+				assert.Equal(t, "@echo off\r\n\"%~dp0gobin.exe\" run stringer %*\r\n", string(V(os.ReadFile(shimPath))))
+			}
+			if strategy != ShimSymlink {
+				// The gobin command which the shims run must be an executable, not a symlink.
+				assert.True(t, V(os.Lstat(gobinCmdPath)).Mode().IsRegular())
+			}
+		})
+	}
+	_, err := EnsureShim(os.TempDir(), "stringer", WithShimStrategy("junction"))
+	assert.ErrorContains(t, err, "unknown shim strategy")
+}
+
+func TestEnsureInstalledError(t *testing.T) {
+	tempDir := V(realpath(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	goCmdPath := filepath.Join(tempDir, "go")
+	V0(os.WriteFile(goCmdPath, []byte("#!/bin/sh\necho \"$@\"\nexit 1\n"), 0755))
+
+	// The failure of the build is returned with nil loggers instead of panicking, with the output of the build.
+	var output bytes.Buffer
+	_, err := EnsureInstalled(tempDir, "example.com/cmd/foo", "v1.0.0", "foo", nil, nil, WithGoCmdPath(goCmdPath), WithOutput(&output))
+	var installErr *InstallError
+	if assert.ErrorAs(t, err, &installErr) {
+		assert.Equal(t, "example.com/cmd/foo", installErr.Pkg)
+		assert.Equal(t, "v1.0.0", installErr.Version)
+		assert.Equal(t, "install -tags foo example.com/cmd/foo@v1.0.0\n", installErr.Output)
+	}
+	assert.Equal(t, installErr.Output, output.String())
+
+	_, _, err = ConfDirPath(WithInitialDir(tempDir), WithRootDir(tempDir))
+	assert.ErrorIs(t, err, ErrNoConfig)
+}
+
+func TestGorootContext(t *testing.T) {
+	tempDir := V(realpath(V(os.MkdirTemp("", "gobin-test"))))
+	t.Cleanup(func() { Ignore(os.RemoveAll(tempDir)) })
+	gorootMutex.Lock()
+	savedGorootPath := gorootPath
+	gorootPath = ""
+	gorootMutex.Unlock()
+	t.Cleanup(func() {
+		gorootMutex.Lock()
+		gorootPath = savedGorootPath
+		gorootMutex.Unlock()
+	})
+	// No SDK is there, and the download hangs.
+	t.Setenv("HOME", tempDir)
+	V0(os.WriteFile(filepath.Join(tempDir, "curl"), []byte("#!/bin/sh\necho 'Downloading' >&2\nexec sleep 10\n"), 0755))
+	t.Setenv("PATH", tempDir+string(filepath.ListSeparator)+os.Getenv("PATH"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	var output bytes.Buffer
+	_, err := GorootContext(ctx, WithOutput(&output))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	// The output of the download goes to the writer.
+	assert.Equal(t, "Downloading\n", output.String())
+	// The cancelled download is not cached.
+	assert.Empty(t, gorootPath)
+	_, err = GorootContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
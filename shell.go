@@ -0,0 +1,137 @@
+package gobin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	. "github.com/knaka/go-utils"
+)
+
+// pathDirs returns the directories to prepend to PATH so that the tools installed by gobin and the go command take precedence. The go command is the one of WithGoCmdPath if specified, and the managed Go SDK is not downloaded then.
+func pathDirs(params *installParams, gobinPath string, withGobinPath bool) (dirs []string, err error) {
+	defer Catch(&err)
+	if withGobinPath {
+		dirs = append(dirs, gobinPath)
+	}
+	if params.goCmdPath != "" {
+		dirs = append(dirs, filepath.Dir(params.goCmdPath))
+		return
+	}
+	dirs = append(dirs, filepath.Join(V(params.goroot()), "bin"))
+	return
+}
+
+// prependedPath returns the value of PATH with the directories prepended.
+func prependedPath(dirs []string) string {
+	return strings.Join(append(dirs, os.Getenv("PATH")), string(filepath.ListSeparator))
+}
+
+// PathDirsEx returns the directories which CommandEx prepends to PATH: the directory of the installed tools and the bin directory of the managed Go SDK, or the directory of the go command of WithGoCmdPath.
+func PathDirsEx(opts ...Option) (dirs []string, err error) {
+	return (&Installer{}).PathDirsEx(opts...)
+}
+
+// PathDirsEx returns the directories which CommandEx prepends to PATH.
+func (installer *Installer) PathDirsEx(opts ...Option) (dirs []string, err error) {
+	defer Catch(&err)
+	params := V(installer.params(opts))
+	_, gobinPath := V2(params.dirs())
+	return pathDirs(params, gobinPath, params.WithGobinPath)
+}
+
+// Shells supported by EnvScriptEx.
+const (
+	ShellSh         = "sh"
+	ShellBash       = "bash"
+	ShellZsh        = "zsh"
+	ShellFish       = "fish"
+	ShellPowerShell = "powershell"
+)
+
+// quoteSh quotes the value for POSIX shells.
+func quoteSh(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// quoteFish quotes the value for fish.
+func quoteFish(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// quotePowerShell quotes the value for PowerShell.
+func quotePowerShell(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// EnvScriptEx returns the script for the shell which prepends the directories of PathDirsEx to PATH, to be evaluated by the shell (e.g. `eval "$(gobin env --shell=bash)"`) or written to direnv's “.envrc”.
+func EnvScriptEx(shell string, opts ...Option) (script string, err error) {
+	return (&Installer{}).EnvScriptEx(shell, opts...)
+}
+
+// EnvScriptEx returns the script for the shell which prepends the directories of PathDirsEx to PATH.
+func (installer *Installer) EnvScriptEx(shell string, opts ...Option) (script string, err error) {
+	defer Catch(&err)
+	dirs := V(installer.PathDirsEx(opts...))
+	switch shell {
+	case ShellSh, ShellBash, ShellZsh:
+		quoted := make([]string, len(dirs))
+		for i, dir := range dirs {
+			quoted[i] = quoteSh(dir)
+		}
+		script = fmt.Sprintf("export PATH=%s:\"$PATH\"\n", strings.Join(quoted, ":"))
+	case ShellFish:
+		quoted := make([]string, len(dirs))
+		for i, dir := range dirs {
+			quoted[i] = quoteFish(dir)
+		}
+		script = fmt.Sprintf("set -gx PATH %s $PATH\n", strings.Join(quoted, " "))
+	case ShellPowerShell:
+		quoted := make([]string, len(dirs))
+		for i, dir := range dirs {
+			quoted[i] = quotePowerShell(dir)
+		}
+		script = fmt.Sprintf("$env:PATH = (@(%s) + $env:PATH) -join [IO.Path]::PathSeparator\n", strings.Join(quoted, ", "))
+	default:
+		err = errors.New(fmt.Sprintf("unsupported shell “%s”", shell))
+	}
+	return
+}
+
+// DefaultShell returns the shell of the user, which is taken from $SHELL, or %ComSpec% on Windows.
+func DefaultShell() string {
+	//goland:noinspection GoBoolExpressions
+	if runtime.GOOS == "windows" {
+		return Elvis(os.Getenv("ComSpec"), "cmd.exe")
+	}
+	return Elvis(os.Getenv("SHELL"), "/bin/sh")
+}
+
+// ShellCommandEx returns the command to spawn the interactive shell of DefaultShell with the directories of PathDirsEx prepended to PATH.
+func ShellCommandEx(opts ...Option) (cmd *exec.Cmd, err error) {
+	return (&Installer{}).ShellCommandEx(opts...)
+}
+
+// ShellCommandEx returns the command to spawn the interactive shell with the directories of PathDirsEx prepended to PATH.
+func (installer *Installer) ShellCommandEx(opts ...Option) (cmd *exec.Cmd, err error) {
+	defer Catch(&err)
+	params := V(installer.params(opts))
+	dirs := V(installer.PathDirsEx(opts...))
+	cmd = exec.CommandContext(params.ctx, DefaultShell())
+	cmd.Stdin = params.stdin
+	cmd.Stdout = params.stdout
+	cmd.Stderr = params.stderr
+	if params.Dir != "" {
+		cmd.Dir = params.Dir
+	}
+	cmd.Env = os.Environ()
+	if params.Env != nil {
+		cmd.Env = append(cmd.Env, params.Env...)
+	}
+	cmd.Env = append(cmd.Env, "PATH="+prependedPath(dirs))
+	return
+}
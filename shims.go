@@ -0,0 +1,56 @@
+package gobin
+
+import (
+	"os"
+	"path"
+
+	. "github.com/knaka/go-utils"
+	"github.com/knaka/gobin/minlib"
+)
+
+// createShims creates the shims of the applicable entries in the gobin directory. An entry which shares its base name with a preceding one or with the gobin command itself gets no shim.
+func createShims(entries []*maniEntry, gobinPath string, strategy minlib.ShimStrategy) (shimPaths []string, err error) {
+	defer Catch(&err)
+	V0(os.MkdirAll(gobinPath, 0755))
+	created := map[string]bool{minlib.GobinCmdBase: true}
+	for _, entry := range entries {
+		if !entry.Applicable {
+			continue
+		}
+		base := path.Base(entry.Pkg)
+		if created[base] {
+			continue
+		}
+		created[base] = true
+		shimPaths = append(shimPaths, V(minlib.EnsureShim(gobinPath, base, minlib.WithShimStrategy(strategy))))
+	}
+	return
+}
+
+// ShimsEx creates the shims of all the applicable manifest entries in the gobin directory up front. A shim runs the gobin command, which installs the locked version of the command on first use and runs it, so that putting the gobin directory on PATH is enough to run any tool.
+func ShimsEx(opts ...Option) (shimPaths []string, err error) {
+	return (&Installer{}).ShimsEx(opts...)
+}
+
+// ShimsEx creates the shims of all the applicable manifest entries in the gobin directory up front.
+func (installer *Installer) ShimsEx(opts ...Option) (shimPaths []string, err error) {
+	defer Catch(&err)
+	params := V(installer.params(opts))
+	global := params.optGlobal != nil && *params.optGlobal
+	confDirPath, gobinPath := V2(params.dirs())
+	// The shims are of no use without the gobin command they link to.
+	V0(minlib.EnsureGobinCmdInstalled(global, params.installOptions()...))
+	manifest := V(parseManifest(confDirPath, withPlatform(params.goos, params.goarch)))
+	var entries []*maniEntry
+	for _, entry := range manifest.Entries() {
+		if entry.inGroups(params.groups) {
+			entries = append(entries, entry)
+		}
+	}
+	return createShims(entries, gobinPath, params.shimStrategy)
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func Shims() (shimPaths []string, err error) {
+	return ShimsEx()
+}
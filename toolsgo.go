@@ -0,0 +1,142 @@
+package gobin
+
+import (
+	"bytes"
+	"go/ast"
+	"go/build/constraint"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	. "github.com/knaka/go-utils"
+)
+
+// toolsBuildTag is the build tag of the files which import the tools to track them in go.mod, conventionally “tools.go”.
+const toolsBuildTag = "tools"
+
+// isToolsConstraint returns true if the build constraint selects the file only with the “tools” tag.
+func isToolsConstraint(line string) bool {
+	expr, err := constraint.Parse(line)
+	if err != nil {
+		return false
+	}
+	return expr.Eval(func(tag string) bool { return tag == toolsBuildTag }) &&
+		!expr.Eval(func(tag string) bool { return false })
+}
+
+// toolsGoFileT is a Go file which imports the tools with the “tools” build tag.
+type toolsGoFileT struct {
+	path string
+	// imports are the paths of the blank imports.
+	imports []string
+}
+
+// toolsGoFiles returns the Go files under the directory which have the “tools” build constraint, with their blank imports. Hidden directories, “vendor”, “testdata” and nested modules are skipped.
+func toolsGoFiles(dirPath string) (files []*toolsGoFileT, err error) {
+	defer Catch(&err)
+	V0(filepath.WalkDir(dirPath, func(filePath string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if dirEntry.IsDir() {
+			if filePath == dirPath {
+				return nil
+			}
+			name := dirEntry.Name()
+			if strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata" {
+				return filepath.SkipDir
+			}
+			if _, err_ := os.Stat(filepath.Join(filePath, goModBase)); err_ == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(filePath, ".go") || strings.HasSuffix(filePath, "_test.go") {
+			return nil
+		}
+		if file := parseToolsGoFile(filePath); file != nil {
+			files = append(files, file)
+		}
+		return nil
+	}))
+	return
+}
+
+// parseToolsGoFile returns the file with its blank imports if it has the “tools” build constraint, or nil otherwise.
+func parseToolsGoFile(filePath string) (file *toolsGoFileT) {
+	astFile, err := parser.ParseFile(token.NewFileSet(), filePath, nil, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		// Files which do not parse are not the concern here.
+		return nil
+	}
+	isTools := false
+	for _, commentGroup := range astFile.Comments {
+		// Build constraints precede the package clause.
+		if commentGroup.Pos() > astFile.Package {
+			break
+		}
+		for _, comment := range commentGroup.List {
+			if constraint.IsGoBuild(comment.Text) && isToolsConstraint(comment.Text) {
+				isTools = true
+			}
+		}
+	}
+	if !isTools {
+		return nil
+	}
+	file = &toolsGoFileT{path: filePath}
+	for _, importSpec := range astFile.Imports {
+		if importSpec.Name == nil || importSpec.Name.Name != "_" {
+			continue
+		}
+		if importPath, err := strconv.Unquote(importSpec.Path.Value); err == nil {
+			file.imports = append(file.imports, importPath)
+		}
+	}
+	return
+}
+
+// isBlankImportOf returns true if the import is a blank import of any of the packages.
+func isBlankImportOf(importSpec *ast.ImportSpec, pkgs []string) bool {
+	importPath, err := strconv.Unquote(importSpec.Path.Value)
+	return err == nil && importSpec.Name != nil && importSpec.Name.Name == "_" && slices.Contains(pkgs, importPath)
+}
+
+// removeImports removes the blank imports of the packages from the file. The file is removed if nothing but the package clause is left.
+func (file *toolsGoFileT) removeImports(pkgs []string) (removed bool, err error) {
+	defer Catch(&err)
+	fileSet := token.NewFileSet()
+	astFile := V(parser.ParseFile(fileSet, file.path, nil, parser.ParseComments))
+	var decls []ast.Decl
+	for _, decl := range astFile.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.IMPORT {
+			decls = append(decls, decl)
+			continue
+		}
+		genDecl.Specs = slices.DeleteFunc(genDecl.Specs, func(spec ast.Spec) bool {
+			return isBlankImportOf(spec.(*ast.ImportSpec), pkgs)
+		})
+		if len(genDecl.Specs) > 0 {
+			decls = append(decls, genDecl)
+		}
+	}
+	if len(decls) == 0 {
+		V0(os.Remove(file.path))
+		return true, nil
+	}
+	astFile.Decls = decls
+	astFile.Imports = slices.DeleteFunc(astFile.Imports, func(importSpec *ast.ImportSpec) bool {
+		return isBlankImportOf(importSpec, pkgs)
+	})
+	buf := &bytes.Buffer{}
+	V0(format.Node(buf, fileSet, astFile))
+	V0(os.WriteFile(file.path, buf.Bytes(), 0644))
+	return
+}